@@ -0,0 +1,478 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package apt // import "pault.ag/go/debian/apt"
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/packet"
+
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/deb"
+)
+
+// Client {{{
+
+// Client fetches indices from APT repositories, checking the signature on
+// the Release file of each suite, and the checksum of each index against
+// the Release file, the same way APT does.
+type Client struct {
+	// The http.Client to make requests with, or nil to use
+	// http.DefaultClient.
+	HTTP *http.Client
+
+	// The keyring to check Release signatures against, for Sources that
+	// don't have their own Signed-By.
+	Keyring *openpgp.EntityList
+
+	// If set, every Release file is checked with the Verifier once its
+	// signature has been checked. Since the Client doesn't cache Release
+	// files, rollback and Suite or Codename changes aren't checked; use
+	// ReleaseVerifier.Verify with the cached Release file for that.
+	Verifier *ReleaseVerifier
+}
+
+// ErrNotFound is returned (wrapped) when the server doesn't have a file.
+var ErrNotFound = errors.New("Not found")
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+// Start a GET request for the given URL, returning the body if the server
+// returned 200 OK.
+func (c *Client) get(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", url, ErrNotFound)
+	}
+	resp.Body.Close()
+	return nil, fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+}
+
+// Fetch the whole body of the given URL into memory.
+func (c *Client) getAll(ctx context.Context, url string) ([]byte, error) {
+	body, err := c.get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+// Return the keyring to check the Release signature of the given Source
+// against; the inline key or keyring files in its Signed-By if it has
+// them, otherwise the Client's Keyring. If Signed-By lists fingerprints,
+// only the keys with (or with a subkey with) one of those fingerprints are
+// used, as APT does. A nil keyring is returned (with no error) only if the
+// Source is Trusted.
+func (c *Client) keyringFor(source *Source) (*openpgp.EntityList, error) {
+	if source.Trusted != nil && *source.Trusted {
+		return nil, nil
+	}
+
+	if source.HasInlineKey() {
+		keyring, err := openpgp.ReadArmoredKeyRing(strings.NewReader(source.SignedBy))
+		if err != nil {
+			return nil, err
+		}
+		return &keyring, nil
+	}
+
+	keyring := openpgp.EntityList{}
+	paths := 0
+	fingerprints := []string{}
+	for _, signedBy := range strings.FieldsFunc(source.SignedBy, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		if !strings.HasPrefix(signedBy, "/") {
+			/* A fingerprint, which may end in a "!" to ask for that
+			 * exact (sub)key */
+			fingerprints = append(fingerprints, strings.ToUpper(strings.TrimSuffix(signedBy, "!")))
+			continue
+		}
+		entities, err := readKeyringFile(signedBy)
+		if err != nil {
+			return nil, err
+		}
+		paths++
+		keyring = append(keyring, entities...)
+	}
+
+	if paths == 0 {
+		/* Fingerprints (if any) select keys from the Client's Keyring */
+		if c.Keyring == nil {
+			return nil, fmt.Errorf("No keyring to check the signature of %s against", strings.Join(source.URIs, " "))
+		}
+		keyring = *c.Keyring
+	}
+
+	if len(fingerprints) == 0 {
+		return &keyring, nil
+	}
+
+	selected := openpgp.EntityList{}
+	for _, entity := range keyring {
+		if entityHasFingerprint(entity, fingerprints) {
+			selected = append(selected, entity)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("No key in the keyring matches the Signed-By of %s", strings.Join(source.URIs, " "))
+	}
+	return &selected, nil
+}
+
+// Return true if the Entity's primary key, or one of its subkeys, has one
+// of the given (upper case hex) fingerprints.
+func entityHasFingerprint(entity *openpgp.Entity, fingerprints []string) bool {
+	keys := []*packet.PublicKey{entity.PrimaryKey}
+	for _, subkey := range entity.Subkeys {
+		keys = append(keys, subkey.PublicKey)
+	}
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+		fingerprint := fmt.Sprintf("%X", key.Fingerprint[:])
+		for _, it := range fingerprints {
+			if it == fingerprint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Read a keyring file, which may either be ASCII armored or binary.
+func readKeyringFile(path string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN PGP")) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(data))
+}
+
+// }}}
+
+// Repository {{{
+
+// Repository is a single suite of an APT Source, whose Release file has
+// been fetched and checked.
+type Repository struct {
+	URI   string
+	Suite string
+
+	Release *control.Release
+
+	// The Entity that signed the Release file, or nil if the Source is
+	// Trusted and the Release file wasn't signed.
+	Signer *openpgp.Entity
+
+	client *Client
+	base   string
+}
+
+// Open each suite of the given Source (every combination of its URIs and
+// Suites), fetching and checking the Release file of each.
+func (c *Client) Open(ctx context.Context, source *Source) ([]*Repository, error) {
+	ret := []*Repository{}
+	for _, uri := range source.URIs {
+		for _, suite := range source.Suites {
+			repo, err := c.OpenSuite(ctx, source, uri, suite)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, repo)
+		}
+	}
+	return ret, nil
+}
+
+// Open a single suite of the given Source. InRelease is fetched first; if
+// the server doesn't have one, Release and Release.gpg are used instead.
+//
+// Unless the Source is Trusted, the Release file must be signed by a key in
+// the keyring for the Source.
+func (c *Client) OpenSuite(ctx context.Context, source *Source, uri, suite string) (*Repository, error) {
+	keyring, err := c.keyringFor(source)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := Repository{
+		URI:    uri,
+		Suite:  suite,
+		client: c,
+		base:   strings.TrimSuffix(uri, "/") + "/dists/" + suite + "/",
+	}
+	if strings.HasSuffix(suite, "/") {
+		/* A flat repository, with no dists directory */
+		repo.base = strings.TrimSuffix(uri, "/") + "/" + strings.TrimPrefix(suite, "/")
+	}
+
+	data, err := c.getAll(ctx, repo.base+"InRelease")
+	switch {
+	case err == nil:
+		decoder, err := control.NewDecoder(bytes.NewReader(data), keyring)
+		if err != nil {
+			return nil, err
+		}
+		repo.Release = &control.Release{}
+		if err := decoder.Decode(repo.Release); err != nil {
+			return nil, err
+		}
+		repo.Signer = decoder.Signer()
+		if keyring != nil && repo.Signer == nil {
+			return nil, fmt.Errorf("%sInRelease is not signed", repo.base)
+		}
+	case errors.Is(err, ErrNotFound):
+		if err := repo.openDetached(ctx, keyring); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if c.Verifier != nil {
+		if err := c.Verifier.Verify(repo.Release, nil); err != nil {
+			return nil, fmt.Errorf("%s: %w", repo.base, err)
+		}
+	}
+	return &repo, nil
+}
+
+// Fetch Release, and check it against Release.gpg.
+func (r *Repository) openDetached(ctx context.Context, keyring *openpgp.EntityList) error {
+	data, err := r.client.getAll(ctx, r.base+"Release")
+	if err != nil {
+		return err
+	}
+
+	if keyring != nil {
+		signature, err := r.client.getAll(ctx, r.base+"Release.gpg")
+		if err != nil {
+			return err
+		}
+		r.Signer, err = openpgp.CheckArmoredDetachedSignature(
+			keyring,
+			bytes.NewReader(data),
+			bytes.NewReader(signature),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.Release, err = control.ParseRelease(bufio.NewReader(bytes.NewReader(data)))
+	return err
+}
+
+// }}}
+
+// Fetching indices {{{
+
+// Return the best variant of the given index (such as
+// `main/binary-amd64/Packages`) listed in the Release file; the smallest
+// one that we know how to decompress.
+func (r *Repository) bestIndex(name string) (*control.FileHash, error) {
+	indices := r.Release.Indices()
+
+	var best *control.FileHash
+	for _, ext := range []string{".xz", ".zst", ".bz2", ".lzma", ".gz", ""} {
+		hash, ok := indices[name+ext]
+		if !ok {
+			continue
+		}
+		if best == nil || hash.Size < best.Size {
+			best = &hash
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%s is not listed in the Release file with a SHA256 or SHA512 checksum", name)
+	}
+	return best, nil
+}
+
+// Fetch the given index, which must be listed in the Release file. The
+// index is downloaded by hash if the Release supports Acquire-By-Hash
+// (falling back to the index's own name if that's not found), and
+// decompressed as it is read.
+//
+// The returned ReadCloser checks the size and checksum of the downloaded
+// data as it's read, and returns an error from Read (rather than io.EOF)
+// if they don't match. Data read from it must not be trusted until it has
+// been read through to io.EOF.
+func (r *Repository) Fetch(ctx context.Context, hash control.FileHash) (io.ReadCloser, error) {
+	return r.fetch(ctx, hash)
+}
+
+func (r *Repository) fetch(ctx context.Context, hash control.FileHash) (*indexReader, error) {
+	body, err := r.client.get(ctx, r.base+r.Release.IndexPath(hash))
+	if errors.Is(err, ErrNotFound) && r.Release.IndexPath(hash) != hash.Filename {
+		body, err = r.client.get(ctx, r.base+hash.Filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := hash.Verifier()
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	checked := &checkedReader{
+		reader:   io.TeeReader(io.LimitReader(body, hash.Size+1), verifier),
+		body:     body,
+		verifier: verifier,
+		size:     hash.Size,
+		name:     hash.Filename,
+	}
+
+	decompressed, err := deb.DecompressorFor(path.Ext(hash.Filename))(checked)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &indexReader{ReadCloser: decompressed, checked: checked}, nil
+}
+
+// Fetch the Packages index of the given component and architecture, and
+// decode it.
+func (r *Repository) Packages(ctx context.Context, component, arch string) ([]control.BinaryIndex, error) {
+	name := component + "/binary-" + arch + "/Packages"
+	if strings.HasSuffix(r.Suite, "/") {
+		name = "Packages"
+	}
+
+	hash, err := r.bestIndex(name)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := r.fetch(ctx, *hash)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	packages, err := control.ParseBinaryIndex(bufio.NewReader(reader))
+	if err != nil {
+		return nil, err
+	}
+	/* Make sure we've read (and checked) the whole file, even if the
+	 * decompressor stopped short. */
+	if _, err := io.Copy(io.Discard, reader.checked); err != nil {
+		return nil, err
+	}
+	if err := reader.checked.finish(); err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
+// checkedReader counts and hashes the raw (compressed) data as it's read,
+// and turns io.EOF into an error if the data doesn't match.
+type checkedReader struct {
+	reader   io.Reader
+	body     io.Closer
+	verifier io.WriteCloser
+	size     int64
+	read     int64
+	name     string
+	err      error
+}
+
+func (c *checkedReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.reader.Read(p)
+	c.read += int64(n)
+	if c.read > c.size {
+		c.err = fmt.Errorf("%s is larger than the Release file says", c.name)
+		return n, c.err
+	}
+	if err == io.EOF {
+		if err := c.finish(); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// Check the size and hash of everything read so far.
+func (c *checkedReader) finish() error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.read != c.size {
+		c.err = fmt.Errorf("%s is %d bytes, but the Release file says %d", c.name, c.read, c.size)
+		return c.err
+	}
+	if err := c.verifier.Close(); err != nil {
+		c.err = fmt.Errorf("%s: %w", c.name, err)
+		return c.err
+	}
+	return nil
+}
+
+// indexReader is the decompressed index, which closes the HTTP body when
+// it's closed.
+type indexReader struct {
+	io.ReadCloser
+	checked *checkedReader
+}
+
+func (i *indexReader) Close() error {
+	i.ReadCloser.Close()
+	return i.checked.body.Close()
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,272 @@
+package apt_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+
+	"pault.ag/go/debian/apt"
+)
+
+const clientPackages = `Package: hello
+Version: 2.10-3
+Architecture: amd64
+Filename: pool/main/h/hello/hello_2.10-3_amd64.deb
+
+Package: vim
+Version: 2:9.0.1378-2
+Architecture: amd64
+Filename: pool/main/v/vim/vim_9.0.1378-2_amd64.deb
+`
+
+// A tiny archive, served over HTTP.
+type testArchive struct {
+	files    map[string][]byte
+	requests []string
+	entity   *openpgp.Entity
+}
+
+func newTestArchive(t *testing.T, byHash bool) *testArchive {
+	entity, err := openpgp.NewEntity("Archive", "", "archive@example.com", nil)
+	isok(t, err)
+
+	archive := testArchive{files: map[string][]byte{}, entity: entity}
+
+	compressed := bytes.Buffer{}
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(clientPackages))
+	writer.Close()
+
+	release := strings.Builder{}
+	release.WriteString("Origin: Test\nSuite: stable\nCodename: testing\n")
+	if byHash {
+		release.WriteString("Acquire-By-Hash: yes\n")
+	}
+	release.WriteString("MD5Sum:\n 00000000000000000000000000000000 1 main/binary-amd64/Packages\nSHA256:\n")
+	for name, data := range map[string][]byte{
+		"main/binary-amd64/Packages":    []byte(clientPackages),
+		"main/binary-amd64/Packages.gz": compressed.Bytes(),
+	} {
+		sum := fmt.Sprintf("%x", sha256.Sum256(data))
+		fmt.Fprintf(&release, " %s %d %s\n", sum, len(data), name)
+		if byHash {
+			archive.files["/debian/dists/stable/main/binary-amd64/by-hash/SHA256/"+sum] = data
+		} else {
+			archive.files["/debian/dists/stable/"+name] = data
+		}
+	}
+
+	archive.publish(t, release.String())
+	return &archive
+}
+
+// Sign the given Release file, and serve it as InRelease, and as Release
+// with Release.gpg.
+func (a *testArchive) publish(t *testing.T, release string) {
+	signed := bytes.Buffer{}
+	plaintext, err := clearsign.Encode(&signed, a.entity.PrivateKey, nil)
+	isok(t, err)
+	plaintext.Write([]byte(release))
+	plaintext.Close()
+	a.files["/debian/dists/stable/InRelease"] = signed.Bytes()
+
+	detached := bytes.Buffer{}
+	isok(t, openpgp.ArmoredDetachSign(&detached, a.entity, strings.NewReader(release), nil))
+	a.files["/debian/dists/stable/Release"] = []byte(release)
+	a.files["/debian/dists/stable/Release.gpg"] = detached.Bytes()
+}
+
+func (a *testArchive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.requests = append(a.requests, r.URL.Path)
+	data, ok := a.files[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(data)
+}
+
+func (a *testArchive) source(server *httptest.Server) *apt.Source {
+	return &apt.Source{
+		Types:      []string{"deb"},
+		URIs:       []string{server.URL + "/debian"},
+		Suites:     []string{"stable"},
+		Components: []string{"main"},
+	}
+}
+
+func TestClientPackages(t *testing.T) {
+	archive := newTestArchive(t, false)
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	client := apt.Client{HTTP: server.Client(), Keyring: &openpgp.EntityList{archive.entity}}
+	repos, err := client.Open(context.Background(), archive.source(server))
+	isok(t, err)
+	assert(t, len(repos) == 1)
+	assert(t, repos[0].Release.Codename == "testing")
+	assert(t, repos[0].Signer != nil)
+
+	packages, err := repos[0].Packages(context.Background(), "main", "amd64")
+	isok(t, err)
+	assert(t, len(packages) == 2)
+	assert(t, packages[1].Package == "vim")
+	assert(t, archive.requests[len(archive.requests)-1] == "/debian/dists/stable/main/binary-amd64/Packages.gz")
+}
+
+func TestClientByHash(t *testing.T) {
+	archive := newTestArchive(t, true)
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	client := apt.Client{HTTP: server.Client(), Keyring: &openpgp.EntityList{archive.entity}}
+	repos, err := client.Open(context.Background(), archive.source(server))
+	isok(t, err)
+
+	packages, err := repos[0].Packages(context.Background(), "main", "amd64")
+	isok(t, err)
+	assert(t, len(packages) == 2)
+	assert(t, strings.Contains(archive.requests[len(archive.requests)-1], "/by-hash/SHA256/"))
+}
+
+func TestClientDetachedRelease(t *testing.T) {
+	archive := newTestArchive(t, false)
+	delete(archive.files, "/debian/dists/stable/InRelease")
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	client := apt.Client{HTTP: server.Client(), Keyring: &openpgp.EntityList{archive.entity}}
+	repos, err := client.Open(context.Background(), archive.source(server))
+	isok(t, err)
+	assert(t, repos[0].Signer != nil)
+	assert(t, repos[0].Release.Suite == "stable")
+
+	/* A bad detached signature */
+	archive.files["/debian/dists/stable/Release"] = append(archive.files["/debian/dists/stable/Release"], '\n')
+	_, err = client.Open(context.Background(), archive.source(server))
+	notok(t, err)
+}
+
+func TestClientRejects(t *testing.T) {
+	archive := newTestArchive(t, false)
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	/* No keyring at all */
+	client := apt.Client{HTTP: server.Client()}
+	_, err := client.Open(context.Background(), archive.source(server))
+	notok(t, err)
+
+	/* Unless the Source is trusted */
+	source := archive.source(server)
+	trusted := true
+	source.Trusted = &trusted
+	_, err = client.Open(context.Background(), source)
+	isok(t, err)
+
+	/* Signed by the wrong key */
+	other, err := openpgp.NewEntity("Other", "", "other@example.com", nil)
+	isok(t, err)
+	client.Keyring = &openpgp.EntityList{other}
+	_, err = client.Open(context.Background(), archive.source(server))
+	notok(t, err)
+
+	/* An unsigned InRelease */
+	client.Keyring = &openpgp.EntityList{archive.entity}
+	archive.files["/debian/dists/stable/InRelease"] = archive.files["/debian/dists/stable/Release"]
+	_, err = client.Open(context.Background(), archive.source(server))
+	notok(t, err)
+}
+
+func TestClientSignedByFingerprint(t *testing.T) {
+	archive := newTestArchive(t, false)
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	other, err := openpgp.NewEntity("Other", "", "other@example.com", nil)
+	isok(t, err)
+	fingerprint := func(entity *openpgp.Entity) string {
+		return fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint[:])
+	}
+
+	client := apt.Client{HTTP: server.Client(), Keyring: &openpgp.EntityList{other, archive.entity}}
+	source := archive.source(server)
+	source.SignedBy = fingerprint(archive.entity)
+	_, err = client.Open(context.Background(), source)
+	isok(t, err)
+
+	/* Subkey fingerprints select the key too */
+	source.SignedBy = fmt.Sprintf("%x!", archive.entity.Subkeys[0].PublicKey.Fingerprint[:])
+	_, err = client.Open(context.Background(), source)
+	isok(t, err)
+
+	/* Only the listed key is trusted, not the whole Keyring */
+	source.SignedBy = fingerprint(other)
+	_, err = client.Open(context.Background(), source)
+	notok(t, err)
+
+	/* A fingerprint that isn't in the Keyring at all */
+	source.SignedBy = strings.Repeat("0", 40)
+	_, err = client.Open(context.Background(), source)
+	notok(t, err)
+}
+
+func TestClientSignedByPathAndFingerprint(t *testing.T) {
+	archive := newTestArchive(t, false)
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	other, err := openpgp.NewEntity("Other", "", "other@example.com", nil)
+	isok(t, err)
+
+	keyring := bytes.Buffer{}
+	isok(t, other.Serialize(&keyring))
+	isok(t, archive.entity.Serialize(&keyring))
+	path := filepath.Join(t.TempDir(), "archive.gpg")
+	isok(t, os.WriteFile(path, keyring.Bytes(), 0644))
+
+	/* The Client's Keyring isn't used when Signed-By has a keyring file */
+	client := apt.Client{HTTP: server.Client(), Keyring: &openpgp.EntityList{archive.entity}}
+	source := archive.source(server)
+	source.SignedBy = fmt.Sprintf("%s, %X", path, archive.entity.PrimaryKey.Fingerprint[:])
+	_, err = client.Open(context.Background(), source)
+	isok(t, err)
+
+	/* The fingerprint selects from the keyring file */
+	source.SignedBy = fmt.Sprintf("%s %X", path, other.PrimaryKey.Fingerprint[:])
+	_, err = client.Open(context.Background(), source)
+	notok(t, err)
+}
+
+func TestClientChecksumMismatch(t *testing.T) {
+	archive := newTestArchive(t, false)
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	client := apt.Client{HTTP: server.Client(), Keyring: &openpgp.EntityList{archive.entity}}
+	repos, err := client.Open(context.Background(), archive.source(server))
+	isok(t, err)
+
+	/* Same size, different data */
+	data := archive.files["/debian/dists/stable/main/binary-amd64/Packages.gz"]
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)-5] ^= 0xff
+	archive.files["/debian/dists/stable/main/binary-amd64/Packages.gz"] = tampered
+	_, err = repos[0].Packages(context.Background(), "main", "amd64")
+	notok(t, err)
+
+	/* Not listed in the Release file */
+	_, err = repos[0].Packages(context.Background(), "contrib", "amd64")
+	notok(t, err)
+}
@@ -0,0 +1,20 @@
+/*
+
+Work with APT configuration and repositories.
+
+This package understands the sources.list(5) formats (both the one-line
+`deb [options] uri suite components` format, and the deb822 `.sources`
+format), and can convert between them. It can also read apt_preferences(5)
+files, and use them to pick the version of a package APT would install.
+
+The Client fetches the Release file of a suite (checking its OpenPGP
+signature), and then the indices listed in it, checking each against the
+checksums in the Release file as it's read. The ReleaseVerifier does the
+rest of APT's checks on a Release file (expiry, clock skew, rollback to an
+older Release file, and weak checksums), and may be set on a Client. A
+local copy of an index can be brought up to date with the ed-style patches
+in its pdiff directory (such as `main/binary-amd64/Packages.diff/`),
+rather than fetching the whole index again.
+
+*/
+package apt // import "pault.ag/go/debian/apt"
@@ -0,0 +1,254 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package apt // import "pault.ag/go/debian/apt"
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/hashio"
+)
+
+// Ed scripts {{{
+
+// An edCommand is a single command of an ed script, as written by
+// `diff --ed`. Line numbers are 1-based and inclusive; `a` appends after
+// line `start`, and `c` and `d` replace or delete lines `start` through
+// `end`.
+type edCommand struct {
+	start int
+	end   int
+	op    byte
+	text  [][]byte
+}
+
+var edCommandRegexp = regexp.MustCompile(`^([0-9]+)(?:,([0-9]+))?([acd])$`)
+
+// Parse an ed script, which must only use the `a`, `c` and `d` commands,
+// with addresses in descending order, as `diff --ed` writes them.
+func parseEdScript(script io.Reader) ([]edCommand, error) {
+	ret := []edCommand{}
+	scanner := bufio.NewScanner(script)
+	scanner.Buffer(nil, 1024*1024)
+
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		match := edCommandRegexp.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			return nil, fmt.Errorf("Unsupported ed command on line %d: '%s'", lineNo, scanner.Text())
+		}
+		command := edCommand{op: match[3][0]}
+		command.start, _ = strconv.Atoi(match[1])
+		command.end = command.start
+		if match[2] != "" {
+			command.end, _ = strconv.Atoi(match[2])
+		}
+		if command.end < command.start || (command.op != 'a' && command.start == 0) {
+			return nil, fmt.Errorf("Bad address on line %d: '%s'", lineNo, scanner.Text())
+		}
+
+		if command.op != 'd' {
+			terminated := false
+			for scanner.Scan() {
+				lineNo++
+				if scanner.Text() == "." {
+					terminated = true
+					break
+				}
+				command.text = append(command.text, []byte(scanner.Text()+"\n"))
+			}
+			if !terminated {
+				return nil, fmt.Errorf("Unterminated text for ed command on line %d", lineNo)
+			}
+		}
+
+		if len(ret) > 0 && command.end >= ret[len(ret)-1].start {
+			return nil, fmt.Errorf("Ed command on line %d is out of order", lineNo)
+		}
+		ret = append(ret, command)
+	}
+	return ret, scanner.Err()
+}
+
+// Apply the ed script (as written by `diff --ed`, and published in pdiff
+// directories) read from `script` to `data`, and return the patched data.
+// Only the `a`, `c` and `d` commands are supported.
+func ApplyEdScript(data []byte, script io.Reader) ([]byte, error) {
+	commands, err := parseEdScript(script)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	/* The commands are in descending order, so each one only touches
+	 * lines that no later command has moved; that lets us walk them
+	 * backwards, copying the untouched lines in between. */
+	out := bytes.Buffer{}
+	out.Grow(len(data))
+	pos := 0
+	for i := len(commands) - 1; i >= 0; i-- {
+		command := commands[i]
+		if command.end > len(lines) {
+			return nil, fmt.Errorf("Ed command addresses line %d, past the end of the file", command.end)
+		}
+
+		keep := command.start - 1
+		if command.op == 'a' {
+			keep = command.start
+		}
+		for _, line := range lines[pos:keep] {
+			out.Write(line)
+		}
+		pos = keep
+		if command.op != 'a' {
+			pos = command.end
+		}
+		for _, line := range command.text {
+			out.Write(line)
+		}
+	}
+	for _, line := range lines[pos:] {
+		out.Write(line)
+	}
+	return out.Bytes(), nil
+}
+
+// }}}
+
+// PDiffs {{{
+
+// Check that `data` matches the given checksum.
+func checkHash(data []byte, want control.FileHash, name string) error {
+	hasher, err := hashio.NewHasher(want.Algorithm)
+	if err != nil {
+		return err
+	}
+	hasher.Write(data)
+	if hasher.Size() != want.Size {
+		return fmt.Errorf("%s is %d bytes, but should be %d", name, hasher.Size(), want.Size)
+	}
+	if got := fmt.Sprintf("%x", hasher.Sum(nil)); got != want.Hash {
+		return fmt.Errorf("%s has the %s checksum %s, but should be %s", name, want.Algorithm, got, want.Hash)
+	}
+	return nil
+}
+
+// Fetch the PDiff Index of the given index (such as
+// `main/binary-amd64/Packages`), which must be listed in the Release file.
+func (r *Repository) PDiffIndex(ctx context.Context, name string) (*control.PDiffIndex, error) {
+	hash, ok := r.Release.Indices()[name+".diff/Index"]
+	if !ok {
+		return nil, fmt.Errorf("%s.diff/Index is not listed in the Release file", name)
+	}
+	reader, err := r.fetch(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	return control.ParsePDiffIndex(bufio.NewReader(bytes.NewReader(data)))
+}
+
+// Bring a local copy of the given index (such as
+// `main/binary-amd64/Packages`) up to date using the patches in its pdiff
+// directory, and return the updated index.
+//
+// Each patch is checked against the PDiff Index before it's applied, and
+// the index is checked after every step. If the Release file lists the
+// uncompressed index, the result is checked against that too. If the local
+// copy is too old (or otherwise not in the history), an error is returned,
+// and the whole index must be fetched instead.
+func (r *Repository) UpdateIndex(ctx context.Context, name string, local []byte) ([]byte, error) {
+	index, err := r.PDiffIndex(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	hasher, err := hashio.NewHasher("sha256")
+	if err != nil {
+		return nil, err
+	}
+	hasher.Write(local)
+	patches, err := index.PatchesFrom(fmt.Sprintf("%x", hasher.Sum(nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, patch := range patches {
+		if err := checkHash(local, patch.From, name); err != nil {
+			return nil, err
+		}
+
+		download := patch.Download
+		download.Filename = name + ".diff/" + download.Filename
+		/* Patches are only ever fetched by name */
+		download.ByHash = ""
+		reader, err := r.fetch(ctx, download)
+		if err != nil {
+			return nil, err
+		}
+		script, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkHash(script, patch.Patch, download.Filename); err != nil {
+			return nil, err
+		}
+
+		local, err = ApplyEdScript(local, bytes.NewReader(script))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", download.Filename, err)
+		}
+		if err := checkHash(local, patch.To, name+" after "+patch.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkHash(local, index.SHA256Current.FileHash, name); err != nil {
+		return nil, err
+	}
+	if want, ok := r.Release.Indices()[name]; ok {
+		if err := checkHash(local, want, name); err != nil {
+			return nil, err
+		}
+	}
+	return local, nil
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,179 @@
+package apt_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+
+	"pault.ag/go/debian/apt"
+)
+
+const pdiffPatched = `Package: hello
+Version: 2.10-4
+Architecture: amd64
+Filename: pool/main/h/hello/hello_2.10-4_amd64.deb
+
+Package: vim
+Version: 2:9.0.1378-2
+Architecture: amd64
+Filename: pool/main/v/vim/vim_9.0.1378-2_amd64.deb
+
+Package: zsh
+Version: 5.9-4
+`
+
+const pdiffFirst = `9a
+
+Package: zsh
+Version: 5.9-4
+.
+4c
+Filename: pool/main/h/hello/hello_2.10-4_amd64.deb
+.
+2c
+Version: 2.10-4
+.
+`
+
+const pdiffRemoved = `Package: hello
+Version: 2.10-4
+Architecture: amd64
+Filename: pool/main/h/hello/hello_2.10-4_amd64.deb
+
+Package: zsh
+Version: 5.9-4
+`
+
+const pdiffSecond = `5,9d
+`
+
+func TestApplyEdScript(t *testing.T) {
+	patched, err := apt.ApplyEdScript([]byte(clientPackages), strings.NewReader(pdiffFirst))
+	isok(t, err)
+	assert(t, string(patched) == pdiffPatched)
+
+	removed, err := apt.ApplyEdScript(patched, strings.NewReader(pdiffSecond))
+	isok(t, err)
+	assert(t, string(removed) == pdiffRemoved)
+
+	/* Appending to an empty file */
+	added, err := apt.ApplyEdScript([]byte{}, strings.NewReader("0a\nPackage: hello\n.\n"))
+	isok(t, err)
+	assert(t, string(added) == "Package: hello\n")
+}
+
+func TestApplyEdScriptErrors(t *testing.T) {
+	for _, script := range []string{
+		"1s/hello/world/\n",
+		"2d\n5d\n",
+		"1a\nPackage: hello\n",
+		"20d\n",
+		"3,1d\n",
+		"0d\n",
+	} {
+		_, err := apt.ApplyEdScript([]byte(clientPackages), strings.NewReader(script))
+		notok(t, err)
+	}
+}
+
+func gzipped(data string) []byte {
+	out := bytes.Buffer{}
+	writer := gzip.NewWriter(&out)
+	writer.Write([]byte(data))
+	writer.Close()
+	return out.Bytes()
+}
+
+func sha256Line(data []byte, name string) string {
+	return fmt.Sprintf(" %x %d %s\n", sha256.Sum256(data), len(data), name)
+}
+
+// Publish a pdiff directory that takes clientPackages to pdiffRemoved in
+// two steps.
+func publishPDiffs(t *testing.T, archive *testArchive, patch string) {
+	const dir = "/debian/dists/stable/main/binary-amd64/Packages.diff/"
+
+	index := strings.Builder{}
+	current := []byte(pdiffRemoved)
+	fmt.Fprintf(&index, "SHA256-Current: %x %d\n", sha256.Sum256(current), len(current))
+	index.WriteString("SHA256-History:\n")
+	index.WriteString(sha256Line([]byte(clientPackages), "T-1-F-0"))
+	index.WriteString(sha256Line([]byte(pdiffPatched), "T-2-F-1"))
+	index.WriteString("SHA256-Patches:\n")
+	index.WriteString(sha256Line([]byte(pdiffFirst), "T-1-F-0"))
+	index.WriteString(sha256Line([]byte(pdiffSecond), "T-2-F-1"))
+	index.WriteString("SHA256-Download:\n")
+	for name, data := range map[string]string{"T-1-F-0.gz": pdiffFirst, "T-2-F-1.gz": pdiffSecond} {
+		compressed := gzipped(data)
+		index.WriteString(sha256Line(compressed, name))
+		archive.files[dir+name] = compressed
+	}
+	/* Serve a different patch than the Index says */
+	archive.files[dir+"T-2-F-1.gz"] = gzipped(patch)
+	archive.files[dir+"Index"] = []byte(index.String())
+
+	release := "Origin: Test\nSuite: stable\nSHA256:\n" +
+		sha256Line(current, "main/binary-amd64/Packages") +
+		sha256Line([]byte(index.String()), "main/binary-amd64/Packages.diff/Index")
+	archive.publish(t, release)
+}
+
+func TestUpdateIndex(t *testing.T) {
+	archive := newTestArchive(t, false)
+	publishPDiffs(t, archive, pdiffSecond)
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	client := apt.Client{HTTP: server.Client(), Keyring: &openpgp.EntityList{archive.entity}}
+	repos, err := client.Open(context.Background(), archive.source(server))
+	isok(t, err)
+
+	index, err := repos[0].PDiffIndex(context.Background(), "main/binary-amd64/Packages")
+	isok(t, err)
+	assert(t, len(index.SHA256History) == 2)
+
+	updated, err := repos[0].UpdateIndex(context.Background(), "main/binary-amd64/Packages", []byte(clientPackages))
+	isok(t, err)
+	assert(t, string(updated) == pdiffRemoved)
+
+	/* Only the second patch is needed from here */
+	requests := len(archive.requests)
+	updated, err = repos[0].UpdateIndex(context.Background(), "main/binary-amd64/Packages", []byte(pdiffPatched))
+	isok(t, err)
+	assert(t, string(updated) == pdiffRemoved)
+	assert(t, len(archive.requests)-requests == 2)
+
+	/* Already current */
+	updated, err = repos[0].UpdateIndex(context.Background(), "main/binary-amd64/Packages", []byte(pdiffRemoved))
+	isok(t, err)
+	assert(t, string(updated) == pdiffRemoved)
+
+	/* Not in the history at all */
+	_, err = repos[0].UpdateIndex(context.Background(), "main/binary-amd64/Packages", []byte("Package: other\n"))
+	notok(t, err)
+
+	/* Not listed in the Release file */
+	_, err = repos[0].UpdateIndex(context.Background(), "main/binary-i386/Packages", []byte(clientPackages))
+	notok(t, err)
+}
+
+func TestUpdateIndexBadPatch(t *testing.T) {
+	archive := newTestArchive(t, false)
+	publishPDiffs(t, archive, "5,8d\n")
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	client := apt.Client{HTTP: server.Client(), Keyring: &openpgp.EntityList{archive.entity}}
+	repos, err := client.Open(context.Background(), archive.source(server))
+	isok(t, err)
+
+	_, err = repos[0].UpdateIndex(context.Background(), "main/binary-amd64/Packages", []byte(clientPackages))
+	notok(t, err)
+}
@@ -0,0 +1,396 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package apt // import "pault.ag/go/debian/apt"
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/version"
+)
+
+// Preferences {{{
+
+// Preference is a single stanza of an apt_preferences(5) file, such as
+// `/etc/apt/preferences.d/backports`.
+type Preference struct {
+	// The package names (or globs, /regular expressions/, or `src:`
+	// source package names) this Preference applies to, or `*` for all
+	// packages.
+	Package     string `required:"true"`
+	Pin         string `required:"true"`
+	PinPriority int    `control:"Pin-Priority" required:"true"`
+	Explanation string
+}
+
+// Parse an apt_preferences(5) file.
+func ParsePreferences(reader io.Reader) ([]Preference, error) {
+	preferences, err := control.DecodeAll[Preference](reader)
+	if err != nil {
+		return nil, err
+	}
+	for _, preference := range preferences {
+		if _, err := parsePin(preference.Pin); err != nil {
+			return nil, err
+		}
+	}
+	return preferences, nil
+}
+
+// Parse the preferences file, and every file in the preferences.d
+// directory of the given APT configuration directory (such as `/etc/apt`),
+// in the order APT reads them. Missing files are skipped.
+func ParsePreferencesDir(dir string) ([]Preference, error) {
+	paths := []string{filepath.Join(dir, "preferences")}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "preferences.d"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		/* APT only reads files without an extension, or ending in
+		 * .pref, made up of a limited set of characters. */
+		name := entry.Name()
+		if entry.IsDir() || !validPreferencesName.MatchString(name) {
+			continue
+		}
+		if ext := filepath.Ext(name); ext != "" && ext != ".pref" {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		paths = append(paths, filepath.Join(dir, "preferences.d", name))
+	}
+
+	ret := []Preference{}
+	for _, path := range paths {
+		fd, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		preferences, err := ParsePreferences(fd)
+		fd.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		ret = append(ret, preferences...)
+	}
+	return ret, nil
+}
+
+var validPreferencesName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Return true if this Preference applies to every package, rather than to
+// specific packages.
+func (p *Preference) Generic() bool {
+	return strings.TrimSpace(p.Package) == "*"
+}
+
+// Return true if this Preference applies to the given package.
+func (p *Preference) MatchesPackage(name, source string) bool {
+	for _, pattern := range strings.Fields(p.Package) {
+		candidate := name
+		if rest, ok := strings.CutPrefix(pattern, "src:"); ok {
+			pattern, candidate = rest, source
+		}
+		if matchPattern(pattern, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// }}}
+
+// Pins {{{
+
+// A pin is the parsed Pin field of a Preference.
+type pin struct {
+	kind string // "version", "release" or "origin"
+
+	/* For version and origin pins */
+	pattern string
+
+	/* For release pins, keyed by the single letter (o, a, n, ...) */
+	release map[string]string
+}
+
+func parsePin(data string) (*pin, error) {
+	kind, rest, _ := strings.Cut(strings.TrimSpace(data), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch kind {
+	case "version", "origin":
+		return &pin{kind: kind, pattern: strings.Trim(rest, `"`)}, nil
+	case "release":
+		ret := pin{kind: kind, release: map[string]string{}}
+		for _, fragment := range strings.Split(rest, ",") {
+			fragment = strings.TrimSpace(fragment)
+			if fragment == "" {
+				continue
+			}
+			key, value, found := strings.Cut(fragment, "=")
+			if !found {
+				/* A bare value is the version of the release */
+				key, value = "v", fragment
+			}
+			switch key {
+			case "a", "n", "v", "o", "l", "c", "b":
+			default:
+				return nil, fmt.Errorf("Unknown release pin '%s' in '%s'", key, data)
+			}
+			ret.release[key] = strings.Trim(value, `"`)
+		}
+		return &ret, nil
+	}
+	return nil, fmt.Errorf("Unknown pin type '%s'", kind)
+}
+
+// Return true if the pin matches the given version.
+func (p *pin) matches(v *PackageVersion) bool {
+	switch p.kind {
+	case "version":
+		return matchPattern(p.pattern, v.Package.Version.String())
+	case "origin":
+		return matchPattern(p.pattern, v.Origin)
+	case "release":
+		if v.Release == nil {
+			return false
+		}
+		for key, pattern := range p.release {
+			value := ""
+			switch key {
+			case "a":
+				value = v.Release.Suite
+			case "n":
+				value = v.Release.Codename
+			case "v":
+				value = v.Release.Version
+			case "o":
+				value = v.Release.Origin
+			case "l":
+				value = v.Release.Label
+			case "c":
+				value = v.Component
+			case "b":
+				value = v.Package.Architecture.String()
+			}
+			if !matchPattern(pattern, value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Match a value against a glob, or a /regular expression/, the way APT
+// does for pins.
+func matchPattern(pattern, value string) bool {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		return err == nil && re.MatchString(value)
+	}
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+// }}}
+
+// Policy {{{
+
+// PackageVersion is a single version of a package, as found in a Packages
+// index of a Release, or in the dpkg status file if it's installed.
+type PackageVersion struct {
+	Package *control.BinaryIndex
+
+	// The Release the Packages index came from, or nil for the installed
+	// version.
+	Release *control.Release
+
+	// The component of the Release (such as `main`) the Packages index
+	// is in.
+	Component string
+
+	// The host name the Release was fetched from, as used by
+	// `Pin: origin` pins.
+	Origin string
+
+	// Set if this is the installed version of the package.
+	Installed bool
+}
+
+// Policy picks the version of a package to install, the same way
+// `apt-cache policy` does.
+type Policy struct {
+	Preferences []Preference
+
+	// The release to prefer, the same as APT::Default-Release. This is
+	// compared against the Suite, Codename and Version of each Release.
+	DefaultRelease string
+}
+
+// Return the priority of the given PackageVersion, without any pins:
+//
+//	100 for the installed version, or a NotAutomatic release that has
+//	    ButAutomaticUpgrades set
+//	  1 for a NotAutomatic release
+//	990 for the DefaultRelease
+//	500 for everything else
+func (p *Policy) defaultPriority(v *PackageVersion) int {
+	switch {
+	case v.Installed || v.Release == nil:
+		return 100
+	case v.Release.NotAutomatic && v.Release.ButAutomaticUpgrades:
+		return 100
+	case v.Release.NotAutomatic:
+		return 1
+	case p.DefaultRelease != "" && (matchPattern(p.DefaultRelease, v.Release.Suite) ||
+		matchPattern(p.DefaultRelease, v.Release.Codename) ||
+		matchPattern(p.DefaultRelease, v.Release.Version)):
+		return 990
+	}
+	return 500
+}
+
+// Return the priority of the index the given PackageVersion came from; the
+// first generic (`Package: *`) Preference that matches, or the default.
+func (p *Policy) indexPriority(v *PackageVersion) int {
+	for _, preference := range p.Preferences {
+		if !preference.Generic() {
+			continue
+		}
+		pin, err := parsePin(preference.Pin)
+		if err != nil || pin.kind == "version" {
+			continue
+		}
+		if pin.matches(v) {
+			return preference.PinPriority
+		}
+	}
+	return p.defaultPriority(v)
+}
+
+// Return the priority of a version of a package, which may be found in
+// more than one place (such as in two Releases, and installed). The first
+// specific Preference that matches any of them wins; otherwise it is the
+// highest priority of the indices the version was found in.
+func (p *Policy) priority(versions []*PackageVersion) int {
+	for _, preference := range p.Preferences {
+		if preference.Generic() {
+			continue
+		}
+		pin, err := parsePin(preference.Pin)
+		if err != nil {
+			continue
+		}
+		for _, v := range versions {
+			if preference.MatchesPackage(v.Package.Package, v.Package.SourcePackage()) && pin.matches(v) {
+				return preference.PinPriority
+			}
+		}
+	}
+
+	max := 0
+	for i, v := range versions {
+		if priority := p.indexPriority(v); i == 0 || priority > max {
+			max = priority
+		}
+	}
+	return max
+}
+
+// Return the pin priority of the given PackageVersion, as shown by
+// `apt-cache policy`.
+func (p *Policy) Priority(v PackageVersion) int {
+	return p.priority([]*PackageVersion{&v})
+}
+
+// Pick the version of a package that APT would install, out of all the
+// PackageVersions known for it (including the installed one, if any), or
+// nil if none of them may be installed.
+//
+// Versions are considered from the highest version down. The version with
+// the highest priority wins, with ties going to the higher version. Versions
+// lower than the installed version are only picked if some version has a
+// priority of at least 1000, and nothing with a priority of 0 or less is
+// ever picked.
+func (p *Policy) Candidate(versions []PackageVersion) *PackageVersion {
+	/* Group the entries by version, since the same version may come
+	 * from more than one place. */
+	groups := map[string][]*PackageVersion{}
+	keys := []version.Version{}
+	for i := range versions {
+		v := &versions[i]
+		key := v.Package.Version.String()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, v.Package.Version)
+		}
+		groups[key] = append(groups[key], v)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return version.Compare(keys[i], keys[j]) > 0
+	})
+
+	/* A priority of 1000 or more allows a downgrade, so we've got to know
+	 * if there are any before we get to the installed version. */
+	priorities := map[string]int{}
+	downgrade := false
+	for key, group := range groups {
+		priorities[key] = p.priority(group)
+		downgrade = downgrade || priorities[key] >= 1000
+	}
+
+	var candidate *PackageVersion
+	max := 0
+	for _, key := range keys {
+		group := groups[key.String()]
+		installed := false
+		for _, v := range group {
+			installed = installed || v.Installed
+		}
+
+		if priority := priorities[key.String()]; priority > max {
+			candidate, max = group[0], priority
+		}
+
+		if installed && !downgrade {
+			break
+		}
+	}
+	return candidate
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,154 @@
+package apt_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/apt"
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/version"
+)
+
+const preferencesFile = `# Prefer backports for the kernel
+Explanation: Newer kernels
+Package: linux-image-* src:firmware-nonfree
+Pin: release n=bookworm-backports
+Pin-Priority: 990
+
+Package: hello
+Pin: version 2.9*
+Pin-Priority: 1001
+
+Package: *
+Pin: release o=Debian,a=experimental
+Pin-Priority: -1
+
+Package: *
+Pin: origin "mirror.example.com"
+Pin-Priority: 700
+`
+
+var (
+	bookworm  = &control.Release{Origin: "Debian", Suite: "stable", Codename: "bookworm"}
+	backports = &control.Release{
+		Origin:               "Debian",
+		Suite:                "stable-backports",
+		Codename:             "bookworm-backports",
+		NotAutomatic:         true,
+		ButAutomaticUpgrades: true,
+	}
+	experimental = &control.Release{Origin: "Debian", Suite: "experimental", Codename: "rc-buggy", NotAutomatic: true}
+)
+
+func packageVersion(t *testing.T, name, ver string, release *control.Release) apt.PackageVersion {
+	v, err := version.Parse(ver)
+	isok(t, err)
+	return apt.PackageVersion{
+		Package:   &control.BinaryIndex{Package: name, Version: v},
+		Release:   release,
+		Component: "main",
+		Origin:    "deb.debian.org",
+		Installed: release == nil,
+	}
+}
+
+func TestParsePreferences(t *testing.T) {
+	preferences, err := apt.ParsePreferences(strings.NewReader(preferencesFile))
+	isok(t, err)
+	assert(t, len(preferences) == 4)
+	assert(t, preferences[0].PinPriority == 990)
+	assert(t, preferences[0].MatchesPackage("linux-image-amd64", "linux-signed-amd64"))
+	assert(t, preferences[0].MatchesPackage("firmware-iwlwifi", "firmware-nonfree"))
+	assert(t, !preferences[0].MatchesPackage("hello", "hello"))
+	assert(t, preferences[2].Generic())
+	assert(t, preferences[2].PinPriority == -1)
+
+	_, err = apt.ParsePreferences(strings.NewReader("Package: *\nPin: wibble foo\nPin-Priority: 1\n"))
+	notok(t, err)
+	_, err = apt.ParsePreferences(strings.NewReader("Package: *\nPin: release q=foo\nPin-Priority: 1\n"))
+	notok(t, err)
+	_, err = apt.ParsePreferences(strings.NewReader("Package: *\nPin: release a=stable\n"))
+	notok(t, err)
+}
+
+func TestParsePreferencesDir(t *testing.T) {
+	dir := t.TempDir()
+	isok(t, os.MkdirAll(filepath.Join(dir, "preferences.d"), 0755))
+	isok(t, os.WriteFile(filepath.Join(dir, "preferences.d", "b.pref"), []byte("Package: b\nPin: version *\nPin-Priority: 2\n"), 0644))
+	isok(t, os.WriteFile(filepath.Join(dir, "preferences.d", "a"), []byte("Package: a\nPin: version *\nPin-Priority: 1\n"), 0644))
+	isok(t, os.WriteFile(filepath.Join(dir, "preferences.d", "c.dpkg-old"), []byte("garbage"), 0644))
+	isok(t, os.WriteFile(filepath.Join(dir, "preferences.d", "d~"), []byte("garbage"), 0644))
+
+	preferences, err := apt.ParsePreferencesDir(dir)
+	isok(t, err)
+	assert(t, len(preferences) == 2)
+	assert(t, preferences[0].Package == "a")
+}
+
+func TestPolicyPriority(t *testing.T) {
+	preferences, err := apt.ParsePreferences(strings.NewReader(preferencesFile))
+	isok(t, err)
+	policy := apt.Policy{Preferences: preferences}
+
+	assert(t, policy.Priority(packageVersion(t, "vim", "2:9.0-1", bookworm)) == 500)
+	assert(t, policy.Priority(packageVersion(t, "vim", "2:9.0-1", nil)) == 100)
+	assert(t, policy.Priority(packageVersion(t, "vim", "2:9.1-1", backports)) == 100)
+	assert(t, policy.Priority(packageVersion(t, "vim", "2:9.2-1", experimental)) == -1)
+	assert(t, policy.Priority(packageVersion(t, "linux-image-amd64", "6.6", backports)) == 990)
+	assert(t, policy.Priority(packageVersion(t, "hello", "2.9-1", bookworm)) == 1001)
+
+	mirror := packageVersion(t, "vim", "2:9.0-1", bookworm)
+	mirror.Origin = "mirror.example.com"
+	assert(t, policy.Priority(mirror) == 700)
+
+	policy.DefaultRelease = "bookworm"
+	assert(t, policy.Priority(packageVersion(t, "vim", "2:9.0-1", bookworm)) == 990)
+	policy.DefaultRelease = "stable"
+	assert(t, policy.Priority(packageVersion(t, "vim", "2:9.0-1", bookworm)) == 990)
+}
+
+func TestPolicyCandidate(t *testing.T) {
+	preferences, err := apt.ParsePreferences(strings.NewReader(preferencesFile))
+	isok(t, err)
+	policy := apt.Policy{Preferences: preferences}
+
+	/* Backports has a higher version, but a lower priority */
+	candidate := policy.Candidate([]apt.PackageVersion{
+		packageVersion(t, "vim", "2:9.1-1", backports),
+		packageVersion(t, "vim", "2:9.0-1", bookworm),
+		packageVersion(t, "vim", "2:9.2-1", experimental),
+	})
+	assert(t, candidate != nil)
+	assert(t, candidate.Package.Version.String() == "2:9.0-1")
+
+	/* Once installed from backports, upgrades come from there */
+	candidate = policy.Candidate([]apt.PackageVersion{
+		packageVersion(t, "vim", "2:9.1-2", backports),
+		packageVersion(t, "vim", "2:9.1-1", nil),
+		packageVersion(t, "vim", "2:9.0-1", bookworm),
+	})
+	assert(t, candidate.Package.Version.String() == "2:9.1-2")
+
+	/* Lower than installed isn't picked, even with a higher priority */
+	candidate = policy.Candidate([]apt.PackageVersion{
+		packageVersion(t, "vim", "2:9.1-1", nil),
+		packageVersion(t, "vim", "2:9.0-1", bookworm),
+	})
+	assert(t, candidate.Package.Version.String() == "2:9.1-1")
+	assert(t, candidate.Installed)
+
+	/* Unless it's pinned over 1000 */
+	candidate = policy.Candidate([]apt.PackageVersion{
+		packageVersion(t, "hello", "2.10-3", nil),
+		packageVersion(t, "hello", "2.9-2", bookworm),
+	})
+	assert(t, candidate.Package.Version.String() == "2.9-2")
+
+	/* Nothing may be installed */
+	candidate = policy.Candidate([]apt.PackageVersion{
+		packageVersion(t, "vim", "2:9.2-1", experimental),
+	})
+	assert(t, candidate == nil)
+}
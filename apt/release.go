@@ -0,0 +1,148 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package apt // import "pault.ag/go/debian/apt"
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"pault.ag/go/debian/control"
+)
+
+// ReleaseVerifier {{{
+
+// The errors returned (wrapped) by ReleaseVerifier.Verify, so that callers
+// can tell which check failed with errors.Is.
+var (
+	ErrReleaseExpired       = errors.New("Release file has expired")
+	ErrReleaseFromFuture    = errors.New("Release file is from the future")
+	ErrReleaseRollback      = errors.New("Release file is older than the cached one")
+	ErrReleaseInfoChanged   = errors.New("Release file changed its Suite or Codename")
+	ErrReleaseWeakChecksums = errors.New("Release file has no strong checksums")
+)
+
+// The default ReleaseVerifier.MaxFutureTime, the same as APT's
+// Acquire::Max-FutureTime.
+const DefaultMaxFutureTime = 10 * time.Second
+
+// ReleaseVerifier checks a Release file the way APT does before trusting
+// it, on top of checking its signature. The zero value does every check,
+// with APT's defaults.
+type ReleaseVerifier struct {
+	// Return the current time, or nil to use time.Now.
+	Now func() time.Time
+
+	// How far past Now the Date of a Release file may be, to allow for
+	// clock skew. If zero, DefaultMaxFutureTime is used.
+	MaxFutureTime time.Duration
+
+	// If set, Release files are considered expired this long after their
+	// Date, even if their Valid-Until is later (or missing), like APT's
+	// Acquire::Max-ValidTime.
+	MaxValidTime time.Duration
+
+	// Don't reject Release files past their Valid-Until, like APT's
+	// Acquire::Check-Valid-Until=false.
+	IgnoreValidUntil bool
+
+	// Accept Release files whose Suite or Codename differ from the cached
+	// Release file, like `apt-get update --allow-releaseinfo-change`.
+	AllowSuiteChange    bool
+	AllowCodenameChange bool
+}
+
+func (v *ReleaseVerifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+// Verify the given Release file. If `previous` is not nil, it is the
+// Release file that was cached the last time this suite was fetched, and
+// the new Release file is also checked against it; it may not be older,
+// and may not change its Suite or Codename.
+//
+// Every index in the Release file must have a SHA256 or SHA512 checksum;
+// indices that only have an MD5Sum or SHA1 checksum are rejected.
+func (v *ReleaseVerifier) Verify(release, previous *control.Release) error {
+	now := v.now()
+
+	maxFutureTime := v.MaxFutureTime
+	if maxFutureTime == 0 {
+		maxFutureTime = DefaultMaxFutureTime
+	}
+	if !release.Date.IsZero() && release.Date.After(now.Add(maxFutureTime)) {
+		return fmt.Errorf("%w (Date: %s)", ErrReleaseFromFuture, release.Date)
+	}
+
+	if !v.IgnoreValidUntil {
+		if !release.ValidUntil.IsZero() && now.After(release.ValidUntil) {
+			return fmt.Errorf("%w (Valid-Until: %s)", ErrReleaseExpired, release.ValidUntil)
+		}
+		if v.MaxValidTime != 0 && !release.Date.IsZero() && now.After(release.Date.Add(v.MaxValidTime)) {
+			return fmt.Errorf("%w (Date: %s, and the maximum age is %s)", ErrReleaseExpired, release.Date, v.MaxValidTime)
+		}
+	}
+
+	if err := verifyChecksums(release); err != nil {
+		return err
+	}
+
+	if previous == nil {
+		return nil
+	}
+	if !previous.Date.IsZero() && release.Date.Before(previous.Date) {
+		return fmt.Errorf("%w (Date: %s, cached Date: %s)", ErrReleaseRollback, release.Date, previous.Date)
+	}
+	if !v.AllowSuiteChange && previous.Suite != release.Suite {
+		return fmt.Errorf("%w (Suite changed from '%s' to '%s')", ErrReleaseInfoChanged, previous.Suite, release.Suite)
+	}
+	if !v.AllowCodenameChange && previous.Codename != release.Codename {
+		return fmt.Errorf("%w (Codename changed from '%s' to '%s')", ErrReleaseInfoChanged, previous.Codename, release.Codename)
+	}
+	return nil
+}
+
+// Make sure every index listed in the Release file has a SHA256 or SHA512
+// checksum.
+func verifyChecksums(release *control.Release) error {
+	indices := release.Indices()
+	if len(indices) == 0 {
+		return ErrReleaseWeakChecksums
+	}
+	for _, hash := range release.MD5Sum {
+		if _, ok := indices[hash.Filename]; !ok {
+			return fmt.Errorf("%w (%s only has an MD5Sum)", ErrReleaseWeakChecksums, hash.Filename)
+		}
+	}
+	for _, hash := range release.SHA1 {
+		if _, ok := indices[hash.Filename]; !ok {
+			return fmt.Errorf("%w (%s only has a SHA1)", ErrReleaseWeakChecksums, hash.Filename)
+		}
+	}
+	return nil
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,123 @@
+package apt_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+
+	"pault.ag/go/debian/apt"
+	"pault.ag/go/debian/control"
+)
+
+const verifyRelease = `Origin: Debian
+Suite: stable
+Codename: bookworm
+Date: Sat, 10 Feb 2024 08:53:05 UTC
+Valid-Until: Sat, 17 Feb 2024 08:53:05 UTC
+MD5Sum:
+ d0a0325a97c42fd5f66a8c3e29bcea64    98581 main/binary-amd64/Packages.xz
+SHA256:
+ 3957f28db16e3f28c7b34ae84f1c929c567de6970f3f1b95dac9b498dd80fe63   738242 main/binary-amd64/Packages.xz
+`
+
+func parseVerifyRelease(t *testing.T, data string) *control.Release {
+	release, err := control.ParseRelease(bufio.NewReader(strings.NewReader(data)))
+	isok(t, err)
+	return release
+}
+
+func at(when string) func() time.Time {
+	return func() time.Time {
+		ret, err := time.Parse(time.RFC3339, when)
+		if err != nil {
+			panic(err)
+		}
+		return ret
+	}
+}
+
+func TestReleaseVerifierDates(t *testing.T) {
+	release := parseVerifyRelease(t, verifyRelease)
+
+	verifier := apt.ReleaseVerifier{Now: at("2024-02-12T00:00:00Z")}
+	isok(t, verifier.Verify(release, nil))
+
+	/* Past Valid-Until */
+	verifier.Now = at("2024-02-18T00:00:00Z")
+	assert(t, errors.Is(verifier.Verify(release, nil), apt.ErrReleaseExpired))
+	verifier.IgnoreValidUntil = true
+	isok(t, verifier.Verify(release, nil))
+
+	/* Older than Max-ValidTime */
+	verifier = apt.ReleaseVerifier{Now: at("2024-02-12T00:00:00Z"), MaxValidTime: 24 * time.Hour}
+	assert(t, errors.Is(verifier.Verify(release, nil), apt.ErrReleaseExpired))
+
+	/* Within the default clock skew */
+	verifier = apt.ReleaseVerifier{Now: at("2024-02-10T08:53:00Z")}
+	isok(t, verifier.Verify(release, nil))
+
+	/* Too far in the future */
+	verifier = apt.ReleaseVerifier{Now: at("2024-02-10T08:00:00Z")}
+	assert(t, errors.Is(verifier.Verify(release, nil), apt.ErrReleaseFromFuture))
+	verifier.MaxFutureTime = time.Hour
+	isok(t, verifier.Verify(release, nil))
+}
+
+func TestReleaseVerifierPrevious(t *testing.T) {
+	verifier := apt.ReleaseVerifier{Now: at("2024-02-12T00:00:00Z")}
+	release := parseVerifyRelease(t, verifyRelease)
+	older := parseVerifyRelease(t, strings.Replace(verifyRelease, "Sat, 10 Feb", "Fri, 09 Feb", 1))
+
+	isok(t, verifier.Verify(release, older))
+	isok(t, verifier.Verify(release, release))
+	assert(t, errors.Is(verifier.Verify(older, release), apt.ErrReleaseRollback))
+
+	renamed := parseVerifyRelease(t, strings.Replace(verifyRelease, "Suite: stable", "Suite: oldstable", 1))
+	assert(t, errors.Is(verifier.Verify(renamed, release), apt.ErrReleaseInfoChanged))
+	verifier.AllowSuiteChange = true
+	isok(t, verifier.Verify(renamed, release))
+
+	recoded := parseVerifyRelease(t, strings.Replace(verifyRelease, "Codename: bookworm", "Codename: trixie", 1))
+	assert(t, errors.Is(verifier.Verify(recoded, release), apt.ErrReleaseInfoChanged))
+	verifier.AllowCodenameChange = true
+	isok(t, verifier.Verify(recoded, release))
+}
+
+func TestReleaseVerifierChecksums(t *testing.T) {
+	verifier := apt.ReleaseVerifier{Now: at("2024-02-12T00:00:00Z")}
+
+	/* Only an MD5Sum */
+	weak := parseVerifyRelease(t, verifyRelease[:strings.Index(verifyRelease, "SHA256:")])
+	assert(t, errors.Is(verifier.Verify(weak, nil), apt.ErrReleaseWeakChecksums))
+
+	/* One index without a strong checksum */
+	partial := parseVerifyRelease(t, strings.Replace(verifyRelease, "MD5Sum:\n", "MD5Sum:\n d0a0325a97c42fd5f66a8c3e29bcea64    98581 main/binary-i386/Packages.xz\n", 1))
+	assert(t, errors.Is(verifier.Verify(partial, nil), apt.ErrReleaseWeakChecksums))
+}
+
+func TestClientVerifier(t *testing.T) {
+	archive := newTestArchive(t, false)
+	server := httptest.NewServer(archive)
+	defer server.Close()
+
+	/* The test archive's Release file has an MD5Sum for an index that it
+	 * also has a SHA256 for, and no Date */
+	client := apt.Client{
+		HTTP:     server.Client(),
+		Keyring:  &openpgp.EntityList{archive.entity},
+		Verifier: &apt.ReleaseVerifier{},
+	}
+	_, err := client.Open(context.Background(), archive.source(server))
+	isok(t, err)
+
+	archive.publish(t, "Origin: Test\nSuite: stable\nValid-Until: Sat, 17 Feb 2024 08:53:05 UTC\n"+
+		"SHA256:\n 3957f28db16e3f28c7b34ae84f1c929c567de6970f3f1b95dac9b498dd80fe63 738242 main/binary-amd64/Packages.xz\n")
+	_, err = client.Open(context.Background(), archive.source(server))
+	assert(t, errors.Is(err, apt.ErrReleaseExpired))
+}
@@ -0,0 +1,447 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package apt // import "pault.ag/go/debian/apt"
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"pault.ag/go/debian/control"
+)
+
+// Source {{{
+
+// Source is a single APT source; a deb822 stanza of a `.sources` file, or
+// one (or more) lines of a `sources.list` file.
+//
+// The deb822 format allows more than one Type, URI and Suite in a single
+// stanza; each combination of them is a line in the one-line format.
+type Source struct {
+	// Set if the Source has been turned off, with `Enabled: no` in the
+	// deb822 format, or by commenting out the line in the one-line format.
+	Disabled bool
+
+	Types         []string
+	URIs          []string
+	Suites        []string
+	Components    []string
+	Architectures []string
+
+	// Either a list of keyring paths and key fingerprints, separated by
+	// spaces, or an ASCII armored OpenPGP public key block.
+	SignedBy string
+
+	// If set, overrides whether APT checks the signature of the
+	// repository at all.
+	Trusted *bool
+
+	// Any other options, keyed by their deb822 field name (such as
+	// `Languages` or `Check-Valid-Until`), with their deb822 values.
+	Options control.Paragraph
+}
+
+// A sourceOption describes how an option in the one-line format maps to a
+// field in the deb822 format.
+type sourceOption struct {
+	field string
+	list  bool
+}
+
+// The options of the one-line format, and the deb822 field each of them is
+// the same as. List options are comma separated in the one-line format,
+// and space separated in the deb822 format.
+var sourceOptions = map[string]sourceOption{
+	"arch":                        {"Architectures", true},
+	"lang":                        {"Languages", true},
+	"target":                      {"Targets", true},
+	"pdiffs":                      {"PDiffs", false},
+	"by-hash":                     {"By-Hash", false},
+	"allow-insecure":              {"Allow-Insecure", false},
+	"allow-weak":                  {"Allow-Weak", false},
+	"allow-downgrade-to-insecure": {"Allow-Downgrade-To-Insecure", false},
+	"trusted":                     {"Trusted", false},
+	"signed-by":                   {"Signed-By", true},
+	"check-valid-until":           {"Check-Valid-Until", false},
+	"valid-until-min":             {"Valid-Until-Min", false},
+	"valid-until-max":             {"Valid-Until-Max", false},
+	"check-date":                  {"Check-Date", false},
+	"date-max-future":             {"Date-Max-Future", false},
+	"inrelease-path":              {"InRelease-Path", false},
+	"snapshot":                    {"Snapshot", false},
+}
+
+// Return the deb822 field for the given one-line option. List options may
+// have a `+` or `-` suffix (as in `arch+=i386`), which are the `-Add` and
+// `-Remove` fields.
+func optionField(option string) (string, bool) {
+	suffix := ""
+	if strings.HasSuffix(option, "+") {
+		option, suffix = option[:len(option)-1], "-Add"
+	} else if strings.HasSuffix(option, "-") {
+		option, suffix = option[:len(option)-1], "-Remove"
+	}
+	if known, ok := sourceOptions[option]; ok {
+		return known.field + suffix, known.list
+	}
+	/* Unknown options are kept as-is, so they survive a round trip */
+	return option + suffix, false
+}
+
+// Return the one-line option for the given deb822 field; the inverse of
+// optionField.
+func fieldOption(field string) (string, bool) {
+	suffix := ""
+	if strings.HasSuffix(field, "-Add") {
+		field, suffix = field[:len(field)-4], "+"
+	} else if strings.HasSuffix(field, "-Remove") {
+		field, suffix = field[:len(field)-7], "-"
+	}
+	for option, known := range sourceOptions {
+		if strings.EqualFold(known.field, field) {
+			return option + suffix, known.list
+		}
+	}
+	return strings.ToLower(field) + suffix, false
+}
+
+// Return true if SignedBy holds an inline OpenPGP key, rather than a list of
+// keyrings and fingerprints.
+func (s *Source) HasInlineKey() bool {
+	return strings.Contains(s.SignedBy, "-----BEGIN PGP PUBLIC KEY BLOCK-----")
+}
+
+// Set a deb822 field on the Source, putting the well-known ones into their
+// own members, and the rest into Options.
+func (s *Source) set(field, value string) error {
+	switch strings.ToLower(field) {
+	case "enabled":
+		enabled, err := parseYesNo(field, value)
+		if err != nil {
+			return err
+		}
+		s.Disabled = !enabled
+	case "types":
+		s.Types = strings.Fields(value)
+	case "uris":
+		s.URIs = strings.Fields(value)
+	case "suites":
+		s.Suites = strings.Fields(value)
+	case "components":
+		s.Components = strings.Fields(value)
+	case "architectures":
+		s.Architectures = strings.Fields(value)
+	case "signed-by":
+		s.SignedBy = strings.TrimSpace(value)
+	case "trusted":
+		trusted, err := parseYesNo(field, value)
+		if err != nil {
+			return err
+		}
+		s.Trusted = &trusted
+	default:
+		if s.Options.Values == nil {
+			s.Options = control.Paragraph{Order: []string{}, Values: map[string]string{}}
+		}
+		s.Options.Set(field, strings.TrimSpace(value))
+	}
+	return nil
+}
+
+func parseYesNo(field, value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("Field '%s' must be yes or no, not '%s'", field, value)
+}
+
+func formatYesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+// }}}
+
+// deb822 format {{{
+
+// Parse a deb822 style `.sources` file.
+func ParseSources(reader io.Reader) ([]Source, error) {
+	paragraphs, err := control.NewParagraphReader(reader, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []Source{}
+	for {
+		para, err := paragraphs.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		source := Source{}
+		for _, key := range para.Order {
+			if err := source.set(key, para.Values[key]); err != nil {
+				return nil, err
+			}
+		}
+		for _, required := range []struct {
+			name   string
+			values []string
+		}{
+			{"Types", source.Types},
+			{"URIs", source.URIs},
+			{"Suites", source.Suites},
+		} {
+			if len(required.values) == 0 {
+				return nil, fmt.Errorf("Required field '%s' is missing!", required.name)
+			}
+		}
+		ret = append(ret, source)
+	}
+	return ret, nil
+}
+
+// Return the Source as a deb822 Paragraph.
+func (s *Source) Paragraph() control.Paragraph {
+	para := control.Paragraph{Order: []string{}, Values: map[string]string{}}
+	add := func(key, value string) {
+		if value != "" {
+			para.Set(key, value)
+		}
+	}
+
+	if s.Disabled {
+		add("Enabled", "no")
+	}
+	add("Types", strings.Join(s.Types, " "))
+	add("URIs", strings.Join(s.URIs, " "))
+	add("Suites", strings.Join(s.Suites, " "))
+	add("Components", strings.Join(s.Components, " "))
+	add("Architectures", strings.Join(s.Architectures, " "))
+	if strings.Contains(s.SignedBy, "\n") {
+		/* Inline keys go under the field name, the way apt writes them */
+		add("Signed-By", "\n"+s.SignedBy)
+	} else {
+		add("Signed-By", s.SignedBy)
+	}
+	if s.Trusted != nil {
+		add("Trusted", formatYesNo(*s.Trusted))
+	}
+	for _, key := range s.Options.Order {
+		add(key, s.Options.Values[key])
+	}
+	return para
+}
+
+// Write the given Sources out as a deb822 style `.sources` file.
+func WriteSources(writer io.Writer, sources []Source) error {
+	for i, source := range sources {
+		if i != 0 {
+			if _, err := io.WriteString(writer, "\n"); err != nil {
+				return err
+			}
+		}
+		para := source.Paragraph()
+		if err := para.WriteTo(writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// }}}
+
+// One-line format {{{
+
+// Parse a single line of a `sources.list` file, such as
+// `deb [arch=amd64 signed-by=/usr/share/keyrings/foo.gpg] http://deb.debian.org/debian bookworm main`.
+// A line that has been commented out is returned as a Disabled Source, and
+// a comment at the end of the line is ignored.
+func ParseSourceLine(line string) (*Source, error) {
+	source := Source{}
+
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") {
+		source.Disabled = true
+		line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+	}
+	line = stripComment(line)
+
+	kind, rest, _ := strings.Cut(line, " ")
+	if kind != "deb" && kind != "deb-src" {
+		return nil, fmt.Errorf("Unknown source type '%s'", kind)
+	}
+	source.Types = []string{kind}
+	rest = strings.TrimSpace(rest)
+
+	if strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end == -1 {
+			return nil, fmt.Errorf("Missing ']' in source line '%s'", line)
+		}
+		for _, option := range strings.Fields(rest[1:end]) {
+			key, value, found := strings.Cut(option, "=")
+			if !found {
+				return nil, fmt.Errorf("Option '%s' has no value", option)
+			}
+			field, list := optionField(key)
+			if list {
+				value = strings.ReplaceAll(value, ",", " ")
+			}
+			if err := source.set(field, value); err != nil {
+				return nil, err
+			}
+		}
+		rest = rest[end+1:]
+	}
+
+	parts := strings.Fields(rest)
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("Source line '%s' has no suite", line)
+	}
+	source.URIs = parts[:1]
+	source.Suites = parts[1:2]
+	source.Components = parts[2:]
+	if len(source.Components) == 0 && !strings.HasSuffix(source.Suites[0], "/") {
+		return nil, fmt.Errorf("Source line '%s' has no components", line)
+	}
+	return &source, nil
+}
+
+// Cut the line at the first '#' that follows whitespace, other than inside
+// the `[...]` options.
+func stripComment(line string) string {
+	options := false
+	for i, r := range line {
+		switch {
+		case r == '[':
+			options = true
+		case r == ']':
+			options = false
+		case r == '#' && !options && i > 0 && (line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimSpace(line[:i])
+		}
+	}
+	return line
+}
+
+// Parse a one-line style `sources.list` file. Comments and blank lines are
+// skipped, other than comments that are themselves a source line, which
+// are returned as Disabled Sources.
+func ParseSourcesList(reader io.Reader) ([]Source, error) {
+	ret := []Source{}
+	scanner := bufio.NewScanner(reader)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		source, err := ParseSourceLine(line)
+		if err != nil {
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			return nil, fmt.Errorf("Line %d: %w", lineNo, err)
+		}
+		ret = append(ret, *source)
+	}
+	return ret, scanner.Err()
+}
+
+// Return the Source in the one-line format, one line for each combination
+// of Types, URIs and Suites. An inline key in SignedBy can't be written in
+// the one-line format, and results in an error.
+func (s *Source) Lines() ([]string, error) {
+	if s.HasInlineKey() {
+		return nil, fmt.Errorf("An inline Signed-By key can't be written as a one-line source")
+	}
+
+	options := []string{}
+	addOption := func(field, value string) {
+		if value == "" {
+			return
+		}
+		option, list := fieldOption(field)
+		if list {
+			value = strings.Join(strings.Fields(value), ",")
+		}
+		options = append(options, option+"="+value)
+	}
+	addOption("Architectures", strings.Join(s.Architectures, " "))
+	addOption("Signed-By", s.SignedBy)
+	if s.Trusted != nil {
+		addOption("Trusted", formatYesNo(*s.Trusted))
+	}
+	for _, key := range s.Options.Order {
+		addOption(key, s.Options.Values[key])
+	}
+
+	prefix := ""
+	if s.Disabled {
+		prefix = "# "
+	}
+
+	ret := []string{}
+	for _, kind := range s.Types {
+		for _, uri := range s.URIs {
+			for _, suite := range s.Suites {
+				parts := []string{prefix + kind}
+				if len(options) > 0 {
+					parts = append(parts, "["+strings.Join(options, " ")+"]")
+				}
+				parts = append(parts, uri, suite)
+				parts = append(parts, s.Components...)
+				ret = append(ret, strings.Join(parts, " "))
+			}
+		}
+	}
+	return ret, nil
+}
+
+// Write the given Sources out as a one-line style `sources.list` file.
+func WriteSourcesList(writer io.Writer, sources []Source) error {
+	for _, source := range sources {
+		lines, err := source.Lines()
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if _, err := io.WriteString(writer, line+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// }}}
+
+// vim: foldmethod=marker
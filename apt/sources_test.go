@@ -0,0 +1,214 @@
+package apt_test
+
+import (
+	"bytes"
+	"log"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/apt"
+)
+
+/*
+ *
+ */
+
+func isok(t *testing.T, err error) {
+	if err != nil {
+		log.Printf("Error! Error is not nil! %s\n", err)
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+func notok(t *testing.T, err error) {
+	if err == nil {
+		log.Printf("Error! Error is nil!\n")
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+func assert(t *testing.T, expr bool) {
+	if !expr {
+		log.Printf("Assertion failed!")
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+/*
+ *
+ */
+
+func TestParseSourceLine(t *testing.T) {
+	source, err := apt.ParseSourceLine("deb [arch=amd64,arm64 signed-by=/usr/share/keyrings/debian.gpg lang=en trusted=no] http://deb.debian.org/debian bookworm main contrib")
+	isok(t, err)
+	assert(t, !source.Disabled)
+	assert(t, source.Types[0] == "deb")
+	assert(t, source.URIs[0] == "http://deb.debian.org/debian")
+	assert(t, source.Suites[0] == "bookworm")
+	assert(t, len(source.Components) == 2)
+	assert(t, len(source.Architectures) == 2)
+	assert(t, source.Architectures[1] == "arm64")
+	assert(t, source.SignedBy == "/usr/share/keyrings/debian.gpg")
+	assert(t, source.Trusted != nil && !*source.Trusted)
+	lang, _ := source.Options.Get("Languages")
+	assert(t, lang == "en")
+
+	source, err = apt.ParseSourceLine("# deb-src http://example.com/ ./")
+	isok(t, err)
+	assert(t, source.Disabled)
+	assert(t, source.Suites[0] == "./")
+	assert(t, len(source.Components) == 0)
+
+	/* Comments at the end of the line aren't Components, but a '#' in
+	 * the options or in the middle of a word isn't a comment. */
+	source, err = apt.ParseSourceLine("deb [signed-by=/etc/apt/keyrings/a#b.gpg] http://deb.debian.org/debian#x bookworm main # mirror")
+	isok(t, err)
+	assert(t, !source.Disabled)
+	assert(t, source.SignedBy == "/etc/apt/keyrings/a#b.gpg")
+	assert(t, source.URIs[0] == "http://deb.debian.org/debian#x")
+	assert(t, len(source.Components) == 1 && source.Components[0] == "main")
+
+	source, err = apt.ParseSourceLine("# deb http://deb.debian.org/debian bookworm main\t# old mirror")
+	isok(t, err)
+	assert(t, source.Disabled)
+	assert(t, len(source.Components) == 1)
+
+	/* A zero Source is enabled */
+	source = &apt.Source{Types: []string{"deb"}, URIs: []string{"http://example.com/"}, Suites: []string{"stable"}, Components: []string{"main"}}
+	enabled := source.Paragraph()
+	_, ok := enabled.Get("Enabled")
+	assert(t, !ok)
+	lines, err := source.Lines()
+	isok(t, err)
+	assert(t, lines[0] == "deb http://example.com/ stable main")
+
+	for _, line := range []string{
+		"rpm http://example.com/ stable main",
+		"deb http://example.com/",
+		"deb http://example.com/ stable",
+		"deb [arch=amd64 http://example.com/ stable main",
+		"deb [arch] http://example.com/ stable main",
+	} {
+		_, err := apt.ParseSourceLine(line)
+		notok(t, err)
+	}
+}
+
+func TestParseSourcesList(t *testing.T) {
+	sources, err := apt.ParseSourcesList(strings.NewReader(`# Main archive
+deb http://deb.debian.org/debian bookworm main
+# deb-src http://deb.debian.org/debian bookworm main
+
+deb [arch+=i386 check-valid-until=no] http://snapshot.debian.org/archive/debian/20240101T000000Z bookworm main
+`))
+	isok(t, err)
+	assert(t, len(sources) == 3)
+	assert(t, sources[1].Disabled)
+	add, _ := sources[2].Options.Get("Architectures-Add")
+	assert(t, add == "i386")
+
+	_, err = apt.ParseSourcesList(strings.NewReader("deb http://example.com/\n"))
+	notok(t, err)
+}
+
+const inlineKeySources = `Types: deb deb-src
+URIs: http://deb.debian.org/debian
+Suites: bookworm bookworm-updates
+Components: main
+Signed-By: /usr/share/keyrings/debian-archive-keyring.gpg
+Languages: en de
+
+# An inline key
+Enabled: no
+Types: deb
+URIs: https://example.com/apt
+Suites: stable
+Components: main
+Architectures: amd64
+Trusted: yes
+Signed-By:
+ -----BEGIN PGP PUBLIC KEY BLOCK-----
+ .
+ mDMEZQ==
+ -----END PGP PUBLIC KEY BLOCK-----
+`
+
+func TestParseSources(t *testing.T) {
+	sources, err := apt.ParseSources(strings.NewReader(inlineKeySources))
+	isok(t, err)
+	assert(t, len(sources) == 2)
+
+	assert(t, !sources[0].Disabled)
+	assert(t, len(sources[0].Types) == 2)
+	assert(t, len(sources[0].Suites) == 2)
+	assert(t, sources[0].Trusted == nil)
+	assert(t, !sources[0].HasInlineKey())
+
+	assert(t, sources[1].Disabled)
+	assert(t, sources[1].Trusted != nil && *sources[1].Trusted)
+	assert(t, sources[1].HasInlineKey())
+	assert(t, strings.HasPrefix(sources[1].SignedBy, "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmDMEZQ=="))
+
+	/* Round trip through the deb822 format */
+	out := bytes.Buffer{}
+	isok(t, apt.WriteSources(&out, sources))
+	again, err := apt.ParseSources(&out)
+	isok(t, err)
+	assert(t, len(again) == 2)
+	assert(t, again[1].SignedBy == sources[1].SignedBy)
+	assert(t, again[1].Disabled)
+	lang, _ := again[0].Options.Get("Languages")
+	assert(t, lang == "en de")
+
+	_, err = apt.ParseSources(strings.NewReader("Types: deb\nSuites: stable\n"))
+	notok(t, err)
+	_, err = apt.ParseSources(strings.NewReader("Types: deb\nURIs: http://example.com\nSuites: stable\nEnabled: maybe\n"))
+	notok(t, err)
+}
+
+func TestSourcesConversion(t *testing.T) {
+	sources, err := apt.ParseSources(strings.NewReader(inlineKeySources))
+	isok(t, err)
+
+	lines, err := sources[0].Lines()
+	isok(t, err)
+	assert(t, len(lines) == 4)
+	assert(t, lines[0] == "deb [signed-by=/usr/share/keyrings/debian-archive-keyring.gpg lang=en,de] http://deb.debian.org/debian bookworm main")
+	assert(t, lines[3] == "deb-src [signed-by=/usr/share/keyrings/debian-archive-keyring.gpg lang=en,de] http://deb.debian.org/debian bookworm-updates main")
+
+	/* Inline keys can't be written in the one-line format */
+	_, err = sources[1].Lines()
+	notok(t, err)
+
+	/* And back again */
+	source, err := apt.ParseSourceLine(lines[0])
+	isok(t, err)
+	assert(t, source.SignedBy == sources[0].SignedBy)
+	lang, _ := source.Options.Get("Languages")
+	assert(t, lang == "en de")
+
+	out := bytes.Buffer{}
+	line := "# deb [arch=amd64 arch-=i386 trusted=yes by-hash=force] http://example.com/ stable main"
+	source, err = apt.ParseSourceLine(line)
+	isok(t, err)
+	isok(t, apt.WriteSources(&out, []apt.Source{*source}))
+	assert(t, out.String() == `Enabled: no
+Types: deb
+URIs: http://example.com/
+Suites: stable
+Components: main
+Architectures: amd64
+Trusted: yes
+Architectures-Remove: i386
+By-Hash: force
+`)
+	again, err := apt.ParseSources(&out)
+	isok(t, err)
+	out.Reset()
+	isok(t, apt.WriteSourcesList(&out, again))
+	assert(t, out.String() == "# deb [arch=amd64 trusted=yes arch-=i386 by-hash=force] http://example.com/ stable main\n")
+}
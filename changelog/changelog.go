@@ -28,19 +28,20 @@ import (
 	"strings"
 	"time"
 
+	"pault.ag/go/debian/control"
 	"pault.ag/go/debian/version"
 )
 
 // A ChangelogEntry is the encapsulation for each entry for a given version
 // in a series of uploads.
 type ChangelogEntry struct {
-	Source    string
-	Version   version.Version
-	Target    string
-	Arguments map[string]string
-	Changelog string
-	ChangedBy string
-	When      time.Time
+	Source    string            `json:"source"`
+	Version   version.Version   `json:"version"`
+	Target    string            `json:"target"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+	Changelog string            `json:"changelog"`
+	ChangedBy control.Person    `json:"changedBy"`
+	When      time.Time         `json:"when"`
 }
 
 const whenLayout = time.RFC1123Z // "Mon, 02 Jan 2006 15:04:05 -0700"
@@ -129,7 +130,11 @@ func ParseOne(reader *bufio.Reader) (*ChangelogEntry, error) {
 	/* Right, so we have a signoff line */
 	_, signoff = partition(signoff, "--")  /* Get rid of the leading " -- " */
 	whom, when := partition(signoff, "  ") /* Split on the "  " */
-	changeLog.ChangedBy = trim(whom)
+	changeLog.ChangedBy, err = control.ParsePerson(trim(whom))
+	if err != nil {
+		/* A sign-off we can't pick apart is still worth having */
+		changeLog.ChangedBy = control.Person{Name: trim(whom)}
+	}
 	changeLog.When, err = time.Parse(whenLayout, trim(when))
 	if err != nil {
 		return nil, fmt.Errorf("Failed parsing When %q: %v", when, err)
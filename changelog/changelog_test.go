@@ -90,7 +90,21 @@ hello (2.9-2) unstable; urgency=low
 func TestChangelogEntry(t *testing.T) {
 	changeLog, err := changelog.ParseOne(bufio.NewReader(strings.NewReader(changeLog)))
 	isok(t, err)
-	assert(t, changeLog.ChangedBy == "Santiago Vila <sanvila@debian.org>")
+	assert(t, changeLog.ChangedBy.Name == "Santiago Vila")
+	assert(t, changeLog.ChangedBy.Email == "sanvila@debian.org")
+}
+
+func TestChangelogEntryUnparsedSignoff(t *testing.T) {
+	changeLog, err := changelog.ParseOne(bufio.NewReader(strings.NewReader(`hello (1.0-1) unstable; urgency=low
+
+  * Initial release.
+
+ -- Santiago Vila <sanvila@debian.org  Thu, 06 Nov 2014 12:03:40 +0100
+`)))
+	isok(t, err)
+	assert(t, changeLog.ChangedBy.Name == "Santiago Vila <sanvila@debian.org")
+	assert(t, changeLog.ChangedBy.Email == "")
+	assert(t, changeLog.When.Year() == 2014)
 }
 
 func TestChangelogEntries(t *testing.T) {
@@ -0,0 +1,119 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package contents // import "pault.ag/go/debian/contents"
+
+import (
+	"archive/tar"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"pault.ag/go/debian/deb"
+)
+
+// Builder {{{
+
+// A Builder collects the files shipped by a set of packages, and writes
+// them out as a Contents index.
+type Builder struct {
+	paths map[string][]Location
+}
+
+// Create a new, empty, Builder.
+func NewBuilder() *Builder {
+	return &Builder{paths: map[string][]Location{}}
+}
+
+// Record that the package at `location` ships `filePath`. Leading `./` and
+// `/` are removed from the path, as they are from a data.tar listing.
+func (b *Builder) Add(filePath string, location Location) {
+	filePath = strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	if filePath == "" {
+		return
+	}
+	for _, existing := range b.paths[filePath] {
+		if existing == location {
+			return
+		}
+	}
+	b.paths[filePath] = append(b.paths[filePath], location)
+}
+
+// Record every file in the data.tar of the given Deb, under the Section and
+// Package of its Control file. Directories are left out, as they are from
+// the Contents indices in the archive.
+//
+// This reads the Deb's Data through to the end, so it can't be used again
+// afterwards.
+func (b *Builder) AddDeb(debFile *deb.Deb) error {
+	location := Location{
+		Section: debFile.Control.Section,
+		Package: debFile.Control.Package,
+	}
+	for {
+		header, err := debFile.Data.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		b.Add(header.Name, location)
+	}
+}
+
+// Return the Entries collected so far, sorted by path, with the Locations
+// of each Entry sorted.
+func (b *Builder) Entries() []Entry {
+	ret := []Entry{}
+	for filePath, locations := range b.paths {
+		sorted := append([]Location{}, locations...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].String() < sorted[j].String()
+		})
+		ret = append(ret, Entry{Path: filePath, Locations: sorted})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret
+}
+
+// Write the collected Entries out as an (uncompressed) Contents index.
+func (b *Builder) WriteTo(out io.Writer) (int64, error) {
+	var written int64
+	for _, entry := range b.Entries() {
+		n, err := io.WriteString(out, entry.String()+"\n")
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// }}}
+
+// vim: foldmethod=marker
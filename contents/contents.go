@@ -0,0 +1,271 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package contents // import "pault.ag/go/debian/contents"
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"pault.ag/go/debian/deb"
+)
+
+// Location {{{
+
+// A Location is a single package that ships a file, qualified by its
+// section (which may include the archive area, such as `contrib/libs`).
+type Location struct {
+	Section string
+	Package string
+}
+
+// Parse a Location, such as `devel/hello`, or `non-free/games/foo`.
+func ParseLocation(data string) Location {
+	i := strings.LastIndex(data, "/")
+	if i == -1 {
+		return Location{Package: data}
+	}
+	return Location{Section: data[:i], Package: data[i+1:]}
+}
+
+func (l Location) String() string {
+	if l.Section == "" {
+		return l.Package
+	}
+	return l.Section + "/" + l.Package
+}
+
+// }}}
+
+// Entry {{{
+
+// An Entry is a single line of a Contents index; a path (relative to the
+// root of the filesystem, with no leading slash), and every package that
+// ships it.
+type Entry struct {
+	Path      string
+	Locations []Location
+}
+
+// Parse a single line of a Contents index. The path may contain
+// whitespace, so the Locations are taken from the last field of the line.
+func ParseEntry(line string) (*Entry, error) {
+	line = strings.TrimRight(line, " \t\r\n")
+	i := strings.LastIndexAny(line, " \t")
+	if i == -1 {
+		return nil, fmt.Errorf("Bad line: '%s' has no package list", line)
+	}
+
+	entry := Entry{Path: strings.TrimRight(line[:i], " \t")}
+	if entry.Path == "" {
+		return nil, fmt.Errorf("Bad line: '%s' has no path", line)
+	}
+	for _, location := range strings.Split(line[i+1:], ",") {
+		if location == "" {
+			return nil, fmt.Errorf("Bad line: '%s' has an empty package", line)
+		}
+		entry.Locations = append(entry.Locations, ParseLocation(location))
+	}
+	return &entry, nil
+}
+
+func (e Entry) String() string {
+	locations := []string{}
+	for _, location := range e.Locations {
+		locations = append(locations, location.String())
+	}
+	return fmt.Sprintf("%-59s %s", e.Path, strings.Join(locations, ","))
+}
+
+// }}}
+
+// Reader {{{
+
+// A Reader streams the Entries of a Contents index, one at a time, without
+// holding the whole index in memory.
+type Reader struct {
+	scanner *bufio.Scanner
+	lineNo  int
+	started bool
+}
+
+// Create a new Reader for the (uncompressed) Contents index read from
+// `reader`.
+func NewReader(reader io.Reader) *Reader {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1024*1024)
+	return &Reader{scanner: scanner}
+}
+
+// Return the next Entry of the Contents index, or io.EOF once the index
+// has been read through.
+//
+// Very old Contents indices start with a free-form preamble, ending with
+// a `FILE LOCATION` header line; that is skipped.
+func (r *Reader) Next() (*Entry, error) {
+	for r.scanner.Scan() {
+		r.lineNo++
+		line := r.scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if !r.started {
+			r.started = true
+			if strings.HasPrefix(line, "This file maps each file") {
+				r.skipPreamble()
+				continue
+			}
+			if isHeader(line) {
+				continue
+			}
+		}
+
+		entry, err := ParseEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %w", r.lineNo, err)
+		}
+		return entry, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Skip through to the `FILE LOCATION` header that ends the preamble.
+func (r *Reader) skipPreamble() {
+	for r.scanner.Scan() {
+		r.lineNo++
+		if isHeader(r.scanner.Text()) {
+			return
+		}
+	}
+}
+
+func isHeader(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) == 2 && fields[0] == "FILE" && fields[1] == "LOCATION"
+}
+
+// }}}
+
+// Index {{{
+
+// An Index is a whole Contents index, held in memory, which can be
+// searched by path or by package.
+type Index struct {
+	Entries []Entry
+
+	paths    map[string]int
+	packages map[string][]string
+}
+
+// Read in a whole (uncompressed) Contents index.
+func ParseIndex(reader io.Reader) (*Index, error) {
+	index := Index{
+		Entries:  []Entry{},
+		paths:    map[string]int{},
+		packages: map[string][]string{},
+	}
+
+	contents := NewReader(reader)
+	for {
+		entry, err := contents.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		index.paths[entry.Path] = len(index.Entries)
+		index.Entries = append(index.Entries, *entry)
+		for _, location := range entry.Locations {
+			index.packages[location.Package] = append(index.packages[location.Package], entry.Path)
+		}
+	}
+	return &index, nil
+}
+
+// Given a path on the filesystem, read in the Contents index, decompressing
+// it based on its extension (such as `.gz` or `.xz`).
+func ParseIndexFile(path string) (*Index, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	reader, err := deb.DecompressorFor(filepath.Ext(path))(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ParseIndex(reader)
+}
+
+// Return the packages that ship the given path. The path may be given with
+// or without a leading slash.
+func (i *Index) Owners(path string) []Location {
+	n, ok := i.paths[strings.TrimPrefix(path, "/")]
+	if !ok {
+		return []Location{}
+	}
+	return i.Entries[n].Locations
+}
+
+// Return the paths shipped by the given package, in the order they are
+// listed in the index, like `apt-file list`.
+func (i *Index) Files(pkg string) []string {
+	return i.packages[pkg]
+}
+
+// Return the names of every package in the index, sorted.
+func (i *Index) Packages() []string {
+	ret := []string{}
+	for pkg := range i.packages {
+		ret = append(ret, pkg)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Return every Entry whose path contains the given string, like
+// `apt-file search`. Paths are matched with a leading slash, so that
+// `/bin/` matches `bin/bash` as well as `usr/bin/hello`.
+func (i *Index) Search(pattern string) []Entry {
+	ret := []Entry{}
+	for _, entry := range i.Entries {
+		if strings.Contains("/"+entry.Path, pattern) {
+			ret = append(ret, entry)
+		}
+	}
+	return ret
+}
+
+// }}}
+
+// vim: foldmethod=marker
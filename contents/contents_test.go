@@ -0,0 +1,181 @@
+package contents_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/contents"
+	"pault.ag/go/debian/deb"
+)
+
+/*
+ *
+ */
+
+func isok(t *testing.T, err error) {
+	if err != nil {
+		log.Printf("Error! Error is not nil! %s\n", err)
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+func notok(t *testing.T, err error) {
+	if err == nil {
+		log.Printf("Error! Error is nil!\n")
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+func assert(t *testing.T, expr bool) {
+	if !expr {
+		log.Printf("Assertion failed!")
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+/*
+ *
+ */
+
+const contentsIndex = `bin/bash                                                    shells/bash
+usr/bin/hello                                               devel/hello
+usr/share/doc/My Documents/README                           doc/foo
+usr/share/doc/shared/README                                 doc/foo,non-free/libs/bar
+`
+
+func TestContentsReader(t *testing.T) {
+	reader := contents.NewReader(strings.NewReader(contentsIndex))
+
+	entry, err := reader.Next()
+	isok(t, err)
+	assert(t, entry.Path == "bin/bash")
+	assert(t, len(entry.Locations) == 1)
+	assert(t, entry.Locations[0].Section == "shells")
+	assert(t, entry.Locations[0].Package == "bash")
+
+	_, err = reader.Next()
+	isok(t, err)
+
+	entry, err = reader.Next()
+	isok(t, err)
+	assert(t, entry.Path == "usr/share/doc/My Documents/README")
+
+	entry, err = reader.Next()
+	isok(t, err)
+	assert(t, len(entry.Locations) == 2)
+	assert(t, entry.Locations[1].Section == "non-free/libs")
+	assert(t, entry.Locations[1].Package == "bar")
+
+	_, err = reader.Next()
+	assert(t, err == io.EOF)
+}
+
+func TestContentsReaderPreamble(t *testing.T) {
+	reader := contents.NewReader(strings.NewReader(`This file maps each file available in the Debian GNU/Linux system to
+the package from which it originates.
+
+FILE                                                    LOCATION
+bin/bash                                                shells/bash
+`))
+	entry, err := reader.Next()
+	isok(t, err)
+	assert(t, entry.Path == "bin/bash")
+	_, err = reader.Next()
+	assert(t, err == io.EOF)
+}
+
+func TestContentsReaderErrors(t *testing.T) {
+	_, err := contents.NewReader(strings.NewReader("bin/bash\n")).Next()
+	notok(t, err)
+
+	_, err = contents.NewReader(strings.NewReader("bin/bash shells/bash,\n")).Next()
+	notok(t, err)
+}
+
+func TestContentsIndex(t *testing.T) {
+	index, err := contents.ParseIndex(strings.NewReader(contentsIndex))
+	isok(t, err)
+
+	assert(t, len(index.Entries) == 4)
+	owners := index.Owners("/usr/share/doc/shared/README")
+	assert(t, len(owners) == 2)
+	assert(t, owners[1].String() == "non-free/libs/bar")
+	assert(t, len(index.Owners("usr/bin/missing")) == 0)
+
+	files := index.Files("foo")
+	assert(t, len(files) == 2)
+	assert(t, files[0] == "usr/share/doc/My Documents/README")
+
+	assert(t, strings.Join(index.Packages(), " ") == "bar bash foo hello")
+	assert(t, len(index.Search("README")) == 2)
+	assert(t, len(index.Search("/bin/")) == 2)
+	assert(t, len(index.Search("/usr/bin")) == 1)
+}
+
+func TestContentsIndexFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Contents-amd64.gz")
+	compressed := bytes.Buffer{}
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(contentsIndex))
+	writer.Close()
+	isok(t, os.WriteFile(path, compressed.Bytes(), 0644))
+
+	index, err := contents.ParseIndexFile(path)
+	isok(t, err)
+	assert(t, len(index.Entries) == 4)
+}
+
+// Create a Deb with the given data.tar listing, without the rest of the
+// .deb around it.
+func testDeb(t *testing.T, control deb.Control, files map[string]byte) *deb.Deb {
+	data := bytes.Buffer{}
+	writer := tar.NewWriter(&data)
+	for name, flag := range files {
+		isok(t, writer.WriteHeader(&tar.Header{Name: name, Typeflag: flag, Mode: 0644}))
+	}
+	isok(t, writer.Close())
+	return &deb.Deb{Control: control, Data: tar.NewReader(&data)}
+}
+
+func TestContentsBuilder(t *testing.T) {
+	builder := contents.NewBuilder()
+
+	isok(t, builder.AddDeb(testDeb(t, deb.Control{Package: "hello", Section: "devel"}, map[string]byte{
+		"./":                        tar.TypeDir,
+		"./usr/bin/":                tar.TypeDir,
+		"./usr/bin/hello":           tar.TypeReg,
+		"./usr/share/doc/README":    tar.TypeReg,
+		"./usr/bin/hello-alternate": tar.TypeSymlink,
+	})))
+	isok(t, builder.AddDeb(testDeb(t, deb.Control{Package: "bar", Section: "non-free/libs"}, map[string]byte{
+		"./usr/share/doc/README": tar.TypeReg,
+	})))
+	builder.Add("/usr/share/doc/README", contents.Location{Section: "devel", Package: "hello"})
+
+	out := bytes.Buffer{}
+	_, err := builder.WriteTo(&out)
+	isok(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert(t, len(lines) == 3)
+	assert(t, strings.HasPrefix(lines[0], "usr/bin/hello "))
+	assert(t, strings.HasSuffix(lines[0], " devel/hello"))
+	assert(t, strings.HasSuffix(lines[2], " devel/hello,non-free/libs/bar"))
+
+	/* And back in again */
+	index, err := contents.ParseIndex(&out)
+	isok(t, err)
+	assert(t, len(index.Owners("usr/share/doc/README")) == 2)
+	assert(t, len(index.Files("hello")) == 3)
+}
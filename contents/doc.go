@@ -0,0 +1,28 @@
+/*
+
+Read, search and write Debian Contents indices.
+
+A Contents index (such as `main/Contents-amd64.gz`) maps every file shipped
+in an archive to the packages that ship it, one line per file:
+
+	usr/bin/hello                                               devel/hello
+	usr/share/doc/README                                        doc/foo,libs/bar
+
+Contents indices aren't deb822, so they can't be read with the control
+package. The Reader streams the Entries of a Contents index; an Index holds
+a whole Contents index in memory, and can be searched by path (like
+`apt-file search`) or by package (like `apt-file list`). A Builder creates
+a Contents index from the data.tar listings of a set of `.deb` files.
+
+Here's a trivial example, which lists the packages shipping a file:
+
+	index, err := contents.ParseIndexFile("Contents-amd64.gz")
+	if err != nil {
+		panic(err)
+	}
+	for _, location := range index.Owners("usr/bin/hello") {
+		fmt.Printf("%s\n", location.Package)
+	}
+
+*/
+package contents // import "pault.ag/go/debian/contents"
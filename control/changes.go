@@ -39,8 +39,8 @@ import (
 type FileListChangesFileHash struct {
 	FileHash
 
-	Component string
-	Priority  string
+	Component string `json:"component"`
+	Priority  string `json:"priority"`
 }
 
 func (c *FileListChangesFileHash) UnmarshalControl(data string) error {
@@ -63,6 +63,11 @@ func (c *FileListChangesFileHash) UnmarshalControl(data string) error {
 	return nil
 }
 
+func (c FileListChangesFileHash) MarshalControl() (string, error) {
+	return fmt.Sprintf("%s %d %s %s %s",
+		c.Hash, c.Size, c.Component, c.Priority, c.Filename), nil
+}
+
 // }}}
 
 // The Changes struct is the default encapsulation of the Debian .changes
@@ -87,13 +92,13 @@ type Changes struct {
 	Origin          string
 	Distribution    string
 	Urgency         string
-	Maintainer      string
-	ChangedBy       string `control:"Changed-By"`
+	Maintainer      Person
+	ChangedBy       Person `control:"Changed-By"`
 	Closes          []string
 	Changes         string
-	ChecksumsSha1   []SHA1FileHash            `control:"Checksums-Sha1" delim:"\n" strip:"\n\r\t "`
-	ChecksumsSha256 []SHA256FileHash          `control:"Checksums-Sha256" delim:"\n" strip:"\n\r\t "`
-	Files           []FileListChangesFileHash `control:"Files" delim:"\n" strip:"\n\r\t "`
+	ChecksumsSha1   []SHA1FileHash            `control:"Checksums-Sha1" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	ChecksumsSha256 []SHA256FileHash          `control:"Checksums-Sha256" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	Files           []FileListChangesFileHash `control:"Files" delim:"\n" strip:"\n\r\t " multiline:"true"`
 }
 
 // Given a path on the filesystem, Parse the file off the disk and return
@@ -139,6 +144,22 @@ func (changes *Changes) AbsFiles() []FileListChangesFileHash {
 	return ret
 }
 
+// Verify the files referenced by the .changes, the same way DSC.Verify
+// checks the files of a .dsc.
+func (changes *Changes) Verify() error {
+	fields := [][]FileHash{{}, {}, {}}
+	for _, hash := range changes.Files {
+		fields[0] = append(fields[0], hash.FileHash)
+	}
+	for _, hash := range changes.ChecksumsSha1 {
+		fields[1] = append(fields[1], hash.FileHash)
+	}
+	for _, hash := range changes.ChecksumsSha256 {
+		fields[2] = append(fields[2], hash.FileHash)
+	}
+	return verifyFiles(filepath.Dir(changes.Filename), fields...)
+}
+
 // Return a DSC struct for the DSC listed in the .changes file. This requires
 // Changes.Filename to be correctly set, and for the .dsc file to exist
 // in the correct place next to the .changes.
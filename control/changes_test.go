@@ -22,6 +22,7 @@ package control_test
 
 import (
 	"bufio"
+	"bytes"
 	"strings"
 	"testing"
 
@@ -77,7 +78,8 @@ Files:
 	changes, err := control.ParseChanges(reader, "")
 	isok(t, err)
 	assert(t, changes.Format == "1.8")
-	assert(t, changes.ChangedBy == "Paul Tagliamonte <paultag@debian.org>")
+	assert(t, changes.ChangedBy.String() == "Paul Tagliamonte <paultag@debian.org>")
+	assert(t, changes.Maintainer.Email == "dput-ng-maint@lists.alioth.debian.org")
 	assert(t, len(changes.Binaries) == 3)
 	assert(t, changes.Binaries[2] == "dput-ng-doc")
 
@@ -133,6 +135,11 @@ Files:
 	assert(t, len(changes.ChecksumsSha1) == 2)
 	assert(t, len(changes.ChecksumsSha256) == 2)
 	assert(t, len(changes.Files) == 2)
+
+	out := bytes.Buffer{}
+	isok(t, control.Marshal(&out, changes))
+	assert(t, strings.Contains(out.String(), "\nChecksums-Sha1:\n cb136f28a8c971d4299cc68e8fdad93a8ca7daf3 1131 dput-ng_1.9.dsc\n"))
+	assert(t, strings.Contains(out.String(), "\nFiles:\n a74c9e3e9fe05d480d24cd43b225ee0c 1131 devel extra dput-ng_1.9.dsc\n"))
 }
 
 // vim: foldmethod=marker
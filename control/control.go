@@ -52,17 +52,25 @@ type Control struct {
 type SourceParagraph struct {
 	Paragraph
 
-	Maintainer  string
-	Uploaders   []string `delim:","`
-	Source      string
-	Priority    string
-	Section     string
-	Description string
+	Maintainer       string
+	Uploaders        []string `delim:","`
+	Source           string
+	Priority         string
+	Section          string
+	Description      string
+	Homepage         string
+	StandardsVersion string `control:"Standards-Version"`
 
 	BuildDepends        dependency.Dependency `control:"Build-Depends"`
+	BuildDependsArch    dependency.Dependency `control:"Build-Depends-Arch"`
 	BuildDependsIndep   dependency.Dependency `control:"Build-Depends-Indep"`
 	BuildConflicts      dependency.Dependency `control:"Build-Conflicts"`
+	BuildConflictsArch  dependency.Dependency `control:"Build-Conflicts-Arch"`
 	BuildConflictsIndep dependency.Dependency `control:"Build-Conflicts-Indep"`
+
+	VcsFields
+	Testsuite         []string `delim:"," strip:"\n\r\t "`
+	TestsuiteTriggers []string `control:"Testsuite-Triggers" delim:"," strip:"\n\r\t "`
 }
 
 // Return a list of all entities that are responsible for the package's
@@ -99,14 +107,14 @@ type BinaryParagraph struct {
 }
 
 func (para *Paragraph) getDependencyField(field string) (*dependency.Dependency, error) {
-	if val, ok := para.Values[field]; ok {
+	if val, ok := para.Get(field); ok {
 		return dependency.Parse(val)
 	}
 	return nil, fmt.Errorf("Field `%s' Missing", field)
 }
 
 func (para *Paragraph) getOptionalDependencyField(field string) dependency.Dependency {
-	val := para.Values[field]
+	val, _ := para.Get(field)
 	dep, err := dependency.Parse(val)
 	if err != nil {
 		return dependency.Dependency{}
@@ -0,0 +1,74 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RFC 2822 dates {{{
+
+// The layouts of RFC 2822 dates as seen in the wild, in the Date and
+// Valid-Until fields of Release files, and the Date field of .changes files.
+// The archive tends to write "UTC" as the zone, where dpkg writes a numeric
+// offset.
+var timeLayouts = []string{
+	time.RFC1123Z,                    // Mon, 02 Jan 2006 15:04:05 -0700
+	time.RFC1123,                     // Mon, 02 Jan 2006 15:04:05 MST
+	"Mon, 2 Jan 2006 15:04:05 -0700", // single digit day
+	"Mon, 2 Jan 2006 15:04:05 MST",   // single digit day
+	"02 Jan 2006 15:04:05 -0700",     // no day of week
+	"2 Jan 2006 15:04:05 -0700",      // no day of week, single digit day
+}
+
+// Parse an RFC 2822 date, as used in control files. Any trailing comment,
+// such as the "(UTC)" in "Mon, 02 Jan 2006 15:04:05 +0000 (UTC)", is
+// ignored.
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if i := strings.Index(value, " ("); i != -1 && strings.HasSuffix(value, ")") {
+		value = value[:i]
+	}
+	for _, layout := range timeLayouts {
+		if when, err := time.Parse(layout, value); err == nil {
+			return when, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Unknown date format: '%s'", value)
+}
+
+// Format a date for a control file. Dates in UTC are written with a "UTC"
+// zone, as the archive does; everything else gets a numeric offset.
+func formatTime(when time.Time) string {
+	if when.IsZero() {
+		return ""
+	}
+	if when.Location() == time.UTC {
+		return when.Format("Mon, 02 Jan 2006 15:04:05 UTC")
+	}
+	return when.Format(time.RFC1123Z)
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -51,13 +51,29 @@ type Unmarshallable interface {
 // Paragraphs into the structs.
 //
 // This code will attempt to unpack it into the struct based on the
-// literal name of the key, compared byte-for-byte. If this is not
+// literal name of the key, compared case-insensitively (as field names
+// are, per Debian Policy 5.1). If this is not
 // OK, the struct tag `control:""` can be used to define the key to use
 // in the RFC822 stream.
 //
+// Fields may be strings, signed or unsigned integers, floats, booleans
+// (which must be "yes" or "no"), time.Time (parsed as an RFC 2822 date, as
+// used by Release and .changes files), slices, or pointers to any of those.
+// Pointer fields are left nil if the key is not present, which allows
+// optional values to be told apart from empty ones. Fields tagged with
+// `required:"true"` must be present, and not empty.
+//
+// If the key is missing, the value of a `default:"..."` tag is unpacked
+// instead. A comma separated list of options may follow the key in the
+// `control:""` tag; `control:",inline"` unpacks the fields of a nested
+// Struct from the same Paragraph (as is always done for Anonymous members),
+// and `control:"Key,omitempty"` only changes how the field is Marshaled.
+//
 // If you're unpacking into a list of strings, you have the option of defining
 // a string to split tokens on (`delim:", "`), and things to strip off each
-// element (`strip:"\n\r\t "`).
+// element (`strip:"\n\r\t "`). Named slice types that implement the
+// Unmarshallable interface (such as People) are handed the whole value
+// instead.
 //
 // If you're unpacking into a struct, the struct will be walked according to
 // the rules above. If you wish to override how this writes to the nested
@@ -122,8 +138,6 @@ func decode(p *ParagraphReader, into reflect.Value) error {
 	default:
 		return fmt.Errorf("Can't Decode into a %s", into.Elem().Type().Name())
 	}
-
-	return nil
 }
 
 // }}}
@@ -136,62 +150,47 @@ func decodeStruct(p Paragraph, into reflect.Value) error {
 		return decodeStruct(p, into.Elem())
 	}
 
-	/* Store the Paragraph type for later use when checking Anonymous
-	 * values. */
-	paragraphType := reflect.TypeOf(Paragraph{})
+	return decodeStructPlan(p, into, planFor(into.Type()))
+}
 
-	/* Right, now, we're going to decode a Paragraph into the struct */
+// Decode a Paragraph into the struct, following the plan we've made for
+// this type.
+func decodeStructPlan(p Paragraph, into reflect.Value, plan *structPlan) error {
+	for _, fieldPlan := range plan.fields {
+		field := into.Field(fieldPlan.index)
 
-	for i := 0; i < into.NumField(); i++ {
-		field := into.Field(i)
-		fieldType := into.Type().Field(i)
+		if fieldPlan.paragraph {
+			/* Neat! Let's give the struct this data */
+			*field.Addr().Interface().(*Paragraph) = p
+			continue
+		}
 
-		if field.Type().Kind() == reflect.Struct {
-			err := decodeStruct(p, field)
-			if err != nil {
+		if fieldPlan.nested {
+			if err := decodeStruct(p, field); err != nil {
 				return err
 			}
 		}
 
-		/* First, let's get the name of the field as we'd index into the
-		 * map[string]string. */
-		paragraphKey := fieldType.Name
-		if it := fieldType.Tag.Get("control"); it != "" {
-			paragraphKey = it
-		}
-
-		if paragraphKey == "-" {
-			/* If the key is "-", lets go ahead and skip it */
+		if !fieldPlan.value {
 			continue
 		}
 
-		/* Now, if we have an Anonymous field, we're either going to
-		 * set it to the Paragraph if it's the Paragraph Anonymous member,
-		 * or, more likely, continue through */
-		if fieldType.Anonymous {
-			if fieldType.Type == paragraphType {
-				/* Neat! Let's give the struct this data */
-				field.Set(reflect.ValueOf(p))
-			} else {
-				/* Otherwise, we're going to avoid doing more maths on it */
-				continue
-			}
+		value, ok := p.Get(fieldPlan.key)
+		if !ok && fieldPlan.def != "" {
+			value, ok = fieldPlan.def, true
 		}
-
-		if value, ok := p.Values[paragraphKey]; ok {
-			if err := decodeStructValue(field, fieldType, value); err != nil {
-				return err
-			}
-			continue
-		} else {
-			if fieldType.Tag.Get("required") == "true" {
-				return fmt.Errorf(
-					"Required field '%s' is missing!",
-					fieldType.Name,
-				)
-			}
+		if fieldPlan.required && strings.TrimSpace(value) == "" {
+			return fmt.Errorf(
+				"Required field '%s' is missing!",
+				fieldPlan.key,
+			)
+		}
+		if !ok {
 			continue
 		}
+		if err := decodeStructValue(field, fieldPlan, value); err != nil {
+			return fmt.Errorf("Field '%s': %w", fieldPlan.key, err)
+		}
 	}
 
 	return nil
@@ -201,28 +200,73 @@ func decodeStruct(p Paragraph, into reflect.Value) error {
 
 // set a struct field value {{{
 
-func decodeStructValue(field reflect.Value, fieldType reflect.StructField, value string) error {
+func decodeStructValue(field reflect.Value, fieldPlan fieldPlan, value string) error {
 	switch field.Type().Kind() {
 	case reflect.String:
 		field.SetString(value)
 		return nil
-	case reflect.Int:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if value == "" {
 			field.SetInt(0)
 			return nil
 		}
-		value, err := strconv.Atoi(value)
+		value, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(value)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			field.SetUint(0)
+			return nil
+		}
+		value, err := strconv.ParseUint(value, 10, field.Type().Bits())
 		if err != nil {
 			return err
 		}
-		field.SetInt(int64(value))
+		field.SetUint(value)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			field.SetFloat(0)
+			return nil
+		}
+		value, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(value)
+		return nil
+	case reflect.Ptr:
+		/* Optional values; allocate something to point at, and unpack
+		 * into that. Fields that aren't present stay nil. */
+		target := reflect.New(field.Type().Elem())
+		if err := decodeStructValue(target.Elem(), fieldPlan, value); err != nil {
+			return err
+		}
+		field.Set(target)
 		return nil
 	case reflect.Slice:
-		return decodeStructValueSlice(field, fieldType, value)
+		/* A named slice type (such as People) may unpack itself, rather
+		 * than being split on the delimiter. */
+		if field.CanAddr() {
+			if unmarshal, ok := field.Addr().Interface().(Unmarshallable); ok {
+				return unmarshal.UnmarshalControl(value)
+			}
+		}
+		return decodeStructValueSlice(field, fieldPlan, value)
 	case reflect.Struct:
-		return decodeStructValueStruct(field, fieldType, value)
+		return decodeStructValueStruct(field, value)
 	case reflect.Bool:
-		field.SetBool(value == "yes")
+		switch {
+		case strings.EqualFold(value, "yes"):
+			field.SetBool(true)
+		case strings.EqualFold(value, "no"):
+			field.SetBool(false)
+		default:
+			return fmt.Errorf("Expected 'yes' or 'no', got '%s'", value)
+		}
 		return nil
 	}
 
@@ -234,7 +278,16 @@ func decodeStructValue(field reflect.Value, fieldType reflect.StructField, value
 
 // set a struct field value of type struct {{{
 
-func decodeStructValueStruct(incoming reflect.Value, incomingField reflect.StructField, data string) error {
+func decodeStructValueStruct(incoming reflect.Value, data string) error {
+	if incoming.Type() == timeType {
+		when, err := parseTime(data)
+		if err != nil {
+			return err
+		}
+		incoming.Set(reflect.ValueOf(when))
+		return nil
+	}
+
 	/* Right, so, we've got a type we don't know what to do with. We should
 	 * grab the method, or throw a shitfit. */
 	elem := incoming.Addr()
@@ -245,7 +298,7 @@ func decodeStructValueStruct(incoming reflect.Value, incomingField reflect.Struc
 
 	return fmt.Errorf(
 		"Type '%s' does not implement control.Unmarshallable",
-		incomingField.Type.Name(),
+		incoming.Type().Name(),
 	)
 }
 
@@ -253,32 +306,33 @@ func decodeStructValueStruct(incoming reflect.Value, incomingField reflect.Struc
 
 // set a struct field value of type slice {{{
 
-func decodeStructValueSlice(field reflect.Value, fieldType reflect.StructField, value string) error {
-	underlyingType := field.Type().Elem()
+func decodeStructValueSlice(field reflect.Value, fieldPlan fieldPlan, value string) error {
+	value = strings.Trim(value, fieldPlan.strip)
 
-	var delim = " "
-	if it := fieldType.Tag.Get("delim"); it != "" {
-		delim = it
-	}
-
-	var strip = ""
-	if it := fieldType.Tag.Get("strip"); it != "" {
-		strip = it
-	}
+	/* This is strings.Split, but without allocating the intermediate
+	 * slice of strings, and decoding directly into a backing array of
+	 * the right size. */
+	count := strings.Count(value, fieldPlan.delim) + 1
+	slice := reflect.MakeSlice(field.Type(), count, count)
 
-	value = strings.Trim(value, strip)
-
-	for _, el := range strings.Split(value, delim) {
-		el = strings.Trim(el, strip)
+	for i := 0; i < count; i++ {
+		el := value
+		if j := strings.Index(value, fieldPlan.delim); j >= 0 {
+			el, value = value[:j], value[j+len(fieldPlan.delim):]
+		}
+		el = strings.Trim(el, fieldPlan.strip)
 
-		targetValue := reflect.New(underlyingType)
-		err := decodeStructValue(targetValue.Elem(), fieldType, el)
+		err := decodeStructValue(slice.Index(i), fieldPlan, el)
 		if err != nil {
 			return err
 		}
-		field.Set(reflect.Append(field, targetValue.Elem()))
 	}
 
+	if field.Len() == 0 {
+		field.Set(slice)
+	} else {
+		field.Set(reflect.AppendSlice(field, slice))
+	}
 	return nil
 }
 
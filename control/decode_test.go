@@ -3,6 +3,7 @@ package control_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"pault.ag/go/debian/control"
 	"pault.ag/go/debian/dependency"
@@ -133,3 +134,92 @@ Extra-Source-Only: yes
 `)))
 	assert(t, foo.ExtraSourceOnly)
 }
+
+func TestCaseInsensitiveUnmarshal(t *testing.T) {
+	foo := TestStruct{}
+	isok(t, control.Unmarshal(&foo, strings.NewReader(`value: foo
+VALUE-TWO: baz
+depends: foo, bar
+`)))
+	assert(t, foo.Value == "foo")
+	assert(t, foo.ValueTwo == "baz")
+	assert(t, foo.Depends.Relations[1].Possibilities[0].Name == "bar")
+
+	dsc := control.DSC{}
+	isok(t, control.Unmarshal(&dsc, strings.NewReader(`Source: hello
+Build-depends: debhelper (>= 9)
+Checksums-SHA256:
+ 0a4f8cc793903e366a84379a651bf1a4542d50823b4bd4e038efcdb85a1af95e 1818 hello_1.0-1.dsc
+`)))
+	assert(t, len(dsc.BuildDepends.Relations) == 1)
+	assert(t, len(dsc.ChecksumsSha256) == 1)
+}
+
+type richTypesStruct struct {
+	Size       int64
+	Count      uint
+	Big        uint64
+	Ratio      float64
+	Date       time.Time
+	ValidUntil *time.Time `control:"Valid-Until"`
+	Priority   *int
+	Essential  bool
+	Required   string `required:"true" control:"Must-Have"`
+}
+
+func TestRichTypesUnmarshal(t *testing.T) {
+	foo := richTypesStruct{}
+	isok(t, control.Unmarshal(&foo, strings.NewReader(`Must-Have: yes
+Size: 9223372036854775807
+Count: 42
+Big: 18446744073709551615
+Ratio: 0.25
+Date: Sat, 14 Aug 2021 07:51:03 UTC
+Essential: yes
+`)))
+	assert(t, foo.Size == 9223372036854775807)
+	assert(t, foo.Count == 42)
+	assert(t, foo.Big == 18446744073709551615)
+	assert(t, foo.Ratio == 0.25)
+	assert(t, foo.Date.Equal(time.Date(2021, 8, 14, 7, 51, 3, 0, time.UTC)))
+	assert(t, foo.ValidUntil == nil)
+	assert(t, foo.Priority == nil)
+	assert(t, foo.Essential)
+
+	foo = richTypesStruct{}
+	isok(t, control.Unmarshal(&foo, strings.NewReader(`Must-Have: yes
+Date: Mon, 16 Nov 2015 21:15:55 -0800
+Valid-Until: Sat, 21 Aug 2021 07:51:03 UTC
+Priority: 500
+Essential: no
+`)))
+	assert(t, foo.Date.Equal(time.Date(2015, 11, 17, 5, 15, 55, 0, time.UTC)))
+	assert(t, foo.ValidUntil != nil)
+	assert(t, foo.ValidUntil.Equal(time.Date(2021, 8, 21, 7, 51, 3, 0, time.UTC)))
+	assert(t, foo.Priority != nil && *foo.Priority == 500)
+	assert(t, !foo.Essential)
+}
+
+func TestRichTypesUnmarshalErrors(t *testing.T) {
+	for _, input := range []string{
+		"Must-Have: yes\nEssential: maybe\n",
+		"Must-Have: yes\nCount: -1\n",
+		"Must-Have: yes\nRatio: half\n",
+		"Must-Have: yes\nDate: yesterday\n",
+		"Must-Have: yes\nPriority: high\n",
+	} {
+		foo := richTypesStruct{}
+		notok(t, control.Unmarshal(&foo, strings.NewReader(input)))
+	}
+}
+
+func TestRequiredUnmarshalNamesField(t *testing.T) {
+	foo := richTypesStruct{}
+	err := control.Unmarshal(&foo, strings.NewReader("Size: 1\n"))
+	notok(t, err)
+	assert(t, strings.Contains(err.Error(), "'Must-Have'"))
+
+	err = control.Unmarshal(&foo, strings.NewReader("Must-Have:\nSize: 1\n"))
+	notok(t, err)
+	assert(t, strings.Contains(err.Error(), "'Must-Have'"))
+}
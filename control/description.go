@@ -0,0 +1,285 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// Description {{{
+
+// A Description is a parsed Description field, as described in Debian
+// Policy, section 5.6.13. The first line of the field is the Synopsis, and
+// the rest of the field is the extended description.
+//
+// The extended description is made up of Paragraphs, which are separated
+// by ` .` lines in the control file. Each line of a Paragraph is kept as
+// it's read from the ParagraphReader, without the leading space of the
+// continuation line; lines that still start with a space (written with two
+// or more spaces in the control file) are verbatim, and must be displayed
+// as-is. All other lines are text, which may be word-wrapped for display.
+type Description struct {
+	Synopsis   string
+	Paragraphs [][]string
+}
+
+// Parse a Description field, as returned by the ParagraphReader.
+func ParseDescription(data string) Description {
+	lines := strings.Split(strings.TrimRight(data, "\n"), "\n")
+	ret := Description{Synopsis: strings.TrimSpace(lines[0])}
+
+	var paragraph []string
+	for _, line := range lines[1:] {
+		if line == "" || line == "." {
+			/* A ` .` separator; the ParagraphReader has already
+			 * turned these into empty lines, but a Description may be
+			 * built from other sources. */
+			ret.Paragraphs = append(ret.Paragraphs, paragraph)
+			paragraph = nil
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	if paragraph != nil || len(ret.Paragraphs) > 0 {
+		ret.Paragraphs = append(ret.Paragraphs, paragraph)
+	}
+	return ret
+}
+
+// Return the Description as a field value, in the form that the
+// ParagraphReader would have returned it, and that Paragraph.WriteTo
+// expects.
+func (d Description) String() string {
+	lines := []string{d.Synopsis}
+	for i, paragraph := range d.Paragraphs {
+		if i != 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, paragraph...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (d *Description) UnmarshalControl(data string) error {
+	*d = ParseDescription(data)
+	return nil
+}
+
+func (d Description) MarshalControl() (string, error) {
+	return d.String(), nil
+}
+
+// Return true if the given line of an extended description is verbatim,
+// rather than text that may be word-wrapped.
+func isVerbatimLine(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+// A descriptionBlock is a run of text or verbatim lines in a Paragraph.
+type descriptionBlock struct {
+	verbatim bool
+	lines    []string
+}
+
+func descriptionBlocks(paragraph []string) []descriptionBlock {
+	ret := []descriptionBlock{}
+	for _, line := range paragraph {
+		verbatim := isVerbatimLine(line)
+		if len(ret) == 0 || ret[len(ret)-1].verbatim != verbatim {
+			ret = append(ret, descriptionBlock{verbatim: verbatim})
+		}
+		ret[len(ret)-1].lines = append(ret[len(ret)-1].lines, line)
+	}
+	return ret
+}
+
+// }}}
+
+// Rendering {{{
+
+// Render the extended description as plain text, with Paragraphs separated
+// by an empty line. The Synopsis is left out, as it's usually displayed on
+// its own, as a title.
+func (d Description) Text() string {
+	paragraphs := []string{}
+	for _, paragraph := range d.Paragraphs {
+		paragraphs = append(paragraphs, strings.Join(paragraph, "\n"))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `#`, `\#`, `*`, `\*`,
+)
+
+// Render the extended description as Markdown. Text lines are escaped, and
+// runs of verbatim lines are written as indented code blocks. Lines that
+// start with `- `, `+ ` or `* ` are left as list items. The Synopsis is
+// left out, as it is for Text.
+func (d Description) Markdown() string {
+	blocks := []string{}
+	for _, paragraph := range d.Paragraphs {
+		for _, block := range descriptionBlocks(paragraph) {
+			lines := []string{}
+			for _, line := range block.lines {
+				if block.verbatim {
+					lines = append(lines, "    "+line)
+					continue
+				}
+				bullet := ""
+				for _, prefix := range []string{"- ", "+ ", "* "} {
+					if strings.HasPrefix(line, prefix) {
+						bullet, line = prefix, line[len(prefix):]
+						break
+					}
+				}
+				lines = append(lines, bullet+markdownEscaper.Replace(line))
+			}
+			blocks = append(blocks, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// Render the extended description as HTML. Runs of text lines are written
+// as `<p>` elements, and runs of verbatim lines as `<pre>` elements, with
+// everything escaped. Verbatim lines keep their leading whitespace, as they
+// do in Text and Markdown. The Synopsis is left out, as it is for Text.
+func (d Description) HTML() string {
+	out := strings.Builder{}
+	for _, paragraph := range d.Paragraphs {
+		for _, block := range descriptionBlocks(paragraph) {
+			if block.verbatim {
+				out.WriteString("<pre>")
+				for i, line := range block.lines {
+					if i != 0 {
+						out.WriteString("\n")
+					}
+					out.WriteString(html.EscapeString(line))
+				}
+				out.WriteString("</pre>\n")
+				continue
+			}
+			out.WriteString("<p>")
+			out.WriteString(html.EscapeString(strings.Join(block.lines, "\n")))
+			out.WriteString("</p>\n")
+		}
+	}
+	return out.String()
+}
+
+// }}}
+
+// Checks {{{
+
+// A DescriptionIssue is a problem found with a Description, named after the
+// lintian tag for the same problem.
+type DescriptionIssue struct {
+	Tag     string
+	Message string
+}
+
+func (i DescriptionIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Tag, i.Message)
+}
+
+// Check the Description of the given package the way lintian does,
+// returning every problem found (or none, if it's fine). The Synopsis
+// should be a short phrase, not a sentence, under 80 characters, and
+// neither the Synopsis nor the extended description lines should be longer
+// than that.
+func (d Description) Check(pkg string) []DescriptionIssue {
+	ret := []DescriptionIssue{}
+	issue := func(tag, format string, args ...interface{}) {
+		ret = append(ret, DescriptionIssue{Tag: tag, Message: fmt.Sprintf(format, args...)})
+	}
+
+	synopsis := d.Synopsis
+	lower := strings.ToLower(synopsis)
+	switch {
+	case synopsis == "":
+		issue("description-synopsis-is-empty", "the synopsis is empty")
+	case lower == strings.ToLower(pkg):
+		issue("description-is-pkg-name", "the synopsis is just the package name")
+	case strings.HasPrefix(lower, strings.ToLower(pkg)+" "):
+		issue("description-starts-with-package-name", "the synopsis starts with the package name")
+	}
+	for _, article := range []string{"a ", "an ", "the "} {
+		if strings.HasPrefix(lower, article) {
+			issue("description-synopsis-starts-with-article", "the synopsis starts with '%s'", strings.TrimSpace(synopsis[:len(article)]))
+		}
+	}
+	if strings.HasSuffix(synopsis, ".") && !strings.HasSuffix(synopsis, "..") && !strings.HasSuffix(synopsis, "etc.") {
+		issue("description-synopsis-might-not-be-phrased-properly", "the synopsis ends with a full stop")
+	}
+	if length := utf8.RuneCountInString(synopsis); length > 80 {
+		issue("description-too-long", "the synopsis is %d characters long", length)
+	}
+	if strings.Contains(synopsis, "\t") {
+		issue("description-contains-tabs", "the synopsis contains a tab")
+	}
+
+	if len(d.Paragraphs) == 0 {
+		issue("extended-description-is-empty", "there is no extended description")
+		return ret
+	}
+
+	for n, paragraph := range d.Paragraphs {
+		if len(paragraph) == 0 {
+			issue("extended-description-contains-empty-paragraph", "paragraph %d of the extended description is empty", n+1)
+			continue
+		}
+		if n == 0 && strings.EqualFold(strings.TrimSpace(paragraph[0]), synopsis) {
+			issue("description-synopsis-is-duplicated", "the extended description starts with the synopsis")
+		}
+		for _, line := range paragraph {
+			if length := utf8.RuneCountInString(line) + 1; length > 80 {
+				issue("extended-description-line-too-long", "line '%s' is %d characters long", line, length)
+			}
+			if strings.Contains(line, "\t") {
+				issue("description-contains-tabs", "line '%s' contains a tab", line)
+			}
+		}
+	}
+	return ret
+}
+
+// }}}
+
+// Description accessors {{{
+
+// Parse the Description of this package.
+func (index *BinaryIndex) GetDescription() Description {
+	return ParseDescription(index.Description)
+}
+
+// Parse the Description of this package.
+func (para *BinaryParagraph) GetDescription() Description {
+	return ParseDescription(para.Description)
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,119 @@
+package control_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+const descriptionParagraph = `Package: hello
+Description: example package based on GNU hello
+ The GNU hello program produces a familiar, friendly greeting.  It
+ allows non-programmers to use a classic computer science tool which
+ would otherwise be unavailable to them.
+ .
+ Features:
+ - greets the world
+ * greets <you> too
+   $ hello --greeting="hi"
+   hi
+ .
+ Seriously though: this is an example of how to do a Debian package.
+`
+
+func parseDescription(t *testing.T) control.Description {
+	indices, err := control.ParseBinaryIndex(bufio.NewReader(strings.NewReader(descriptionParagraph)))
+	isok(t, err)
+	return indices[0].GetDescription()
+}
+
+func TestDescriptionParse(t *testing.T) {
+	description := parseDescription(t)
+	assert(t, description.Synopsis == "example package based on GNU hello")
+	assert(t, len(description.Paragraphs) == 3)
+	assert(t, len(description.Paragraphs[0]) == 3)
+	assert(t, description.Paragraphs[1][0] == "Features:")
+	assert(t, description.Paragraphs[1][3] == `  $ hello --greeting="hi"`)
+
+	/* And back out the same way */
+	indices, err := control.ParseBinaryIndex(bufio.NewReader(strings.NewReader(descriptionParagraph)))
+	isok(t, err)
+	assert(t, description.String() == strings.TrimSuffix(indices[0].Description, "\n"))
+
+	synopsis := control.ParseDescription("just a synopsis\n")
+	assert(t, synopsis.Synopsis == "just a synopsis")
+	assert(t, len(synopsis.Paragraphs) == 0)
+	assert(t, synopsis.String() == "just a synopsis")
+}
+
+func TestDescriptionRender(t *testing.T) {
+	description := parseDescription(t)
+
+	text := description.Text()
+	assert(t, strings.HasPrefix(text, "The GNU hello program"))
+	assert(t, strings.Contains(text, "them.\n\nFeatures:\n"))
+	assert(t, strings.Contains(text, "\n  $ hello"))
+
+	markdown := description.Markdown()
+	assert(t, strings.Contains(markdown, "Features:\n- greets the world\n* greets \\<you\\> too\n\n"))
+	assert(t, strings.Contains(markdown, "\n      $ hello --greeting=\"hi\"\n      hi\n"))
+
+	rendered := description.HTML()
+	assert(t, strings.HasPrefix(rendered, "<p>The GNU hello program"))
+	assert(t, strings.Contains(rendered, "* greets &lt;you&gt; too</p>\n<pre>  $ hello --greeting=&#34;hi&#34;\n  hi</pre>\n"))
+	assert(t, strings.HasSuffix(rendered, "<p>Seriously though: this is an example of how to do a Debian package.</p>\n"))
+}
+
+func hasIssue(issues []control.DescriptionIssue, tag string) bool {
+	for _, issue := range issues {
+		if issue.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDescriptionCheck(t *testing.T) {
+	assert(t, len(parseDescription(t).Check("hello")) == 0)
+
+	for tag, description := range map[string]string{
+		"description-synopsis-is-empty":                      "\nLong description.",
+		"description-is-pkg-name":                            "Hello\nLong description.",
+		"description-starts-with-package-name":               "hello is a program\nLong description.",
+		"description-synopsis-starts-with-article":           "A friendly greeting\nLong description.",
+		"description-synopsis-might-not-be-phrased-properly": "friendly greeting.\nLong description.",
+		"description-too-long":                               strings.Repeat("greeting ", 10) + "\nLong description.",
+		"description-contains-tabs":                          "friendly greeting\nLong\tdescription.",
+		"extended-description-is-empty":                      "friendly greeting",
+		"extended-description-contains-empty-paragraph":      "friendly greeting\nLong description.\n\n\nMore.",
+		"extended-description-line-too-long":                 "friendly greeting\n" + strings.Repeat("long ", 16),
+		"description-synopsis-is-duplicated":                 "friendly greeting\nFriendly greeting",
+	} {
+		issues := control.ParseDescription(description).Check("hello")
+		if !hasIssue(issues, tag) {
+			t.Fatalf("Expected %s, got %v", tag, issues)
+		}
+	}
+
+	/* Not quite too long */
+	issues := control.ParseDescription("friendly greeting\n" + strings.Repeat("x", 79)).Check("hello")
+	assert(t, len(issues) == 0)
+	assert(t, !hasIssue(control.ParseDescription("greets people, etc.\nMore.").Check("hello"), "description-synopsis-might-not-be-phrased-properly"))
+}
+
+func TestDescriptionField(t *testing.T) {
+	type Package struct {
+		Package     string
+		Description control.Description
+	}
+	pkg := Package{}
+	isok(t, control.Unmarshal(&pkg, strings.NewReader(descriptionParagraph)))
+	assert(t, pkg.Description.Synopsis == "example package based on GNU hello")
+	assert(t, len(pkg.Description.Paragraphs) == 3)
+
+	out := strings.Builder{}
+	isok(t, control.Marshal(&out, pkg))
+	assert(t, out.String() == descriptionParagraph)
+}
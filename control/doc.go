@@ -2,5 +2,36 @@
 
 Parse the Debian control file format.
 
+JSON
+
+The Paragraph types in this package (DSC, Changes, BinaryIndex, SourceIndex,
+SourceParagraph, BinaryParagraph and Release) are written out as a JSON
+object by MarshalJSON, and read back in by UnmarshalJSON. The schema is:
+
+  - Object keys are the control field names, as used in the control file
+    (`Build-Depends`, `Checksums-Sha256`), in the order the fields are
+    declared on the Struct. Keys are matched case-insensitively when read.
+  - Fields holding the zero value for their type are left out.
+  - Strings, numbers and booleans are written as JSON strings, numbers and
+    booleans; lists (such as `Binary`) are JSON arrays.
+  - A version.Version is a string, such as "1:2.3-1".
+  - A dependency.Arch is a string, such as "amd64" or "kfreebsd-any".
+  - A dependency.Dependency is a tree; see the dependency package.
+  - A Person (such as the Maintainer) is an object with the keys "name"
+    and "email"; People (such as the Uploaders) are an array of them.
+  - A FileHash is an object with the keys "algorithm", "hash", "size",
+    "filename" and (if set) "byHash". Changes file list entries also have
+    "component" and "priority".
+  - Any other keys of the underlying Paragraph (such as `X-*` keys) are
+    written after the Struct fields, as strings. Relationship fields (such
+    as Depends or Provides on a BinaryIndex) are always written as a
+    dependency.Dependency tree, even when the Struct keeps them as strings.
+
+YAML
+
+The same types are written out as a YAML mapping by MarshalYAML, and read
+back in by UnmarshalYAML, for use with gopkg.in/yaml.v3. The schema (and
+the order of the keys) is the same as the JSON schema above.
+
 */
 package control // import "pault.ag/go/debian/control"
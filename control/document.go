@@ -0,0 +1,392 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Document {{{
+
+// A Document is a deb822 file (such as debian/control) that has been read
+// in a way that allows it to be written back out exactly as it was read.
+// Unlike the ParagraphReader, comments, blank lines, whitespace and the
+// original folding of every field are kept around.
+//
+// Fields may be changed using the DocumentParagraph Set and Delete methods;
+// only the fields that have been changed are re-formatted when the Document
+// is written back out. Everything else is written byte-for-byte as it was
+// read.
+type Document struct {
+	Paragraphs []*DocumentParagraph
+
+	/* Blank lines and comments after the last Paragraph */
+	trailer []string
+	newline string
+}
+
+// A DocumentParagraph is a single Paragraph of a Document.
+type DocumentParagraph struct {
+	/* Blank lines and comments before the first field */
+	leading []string
+	entries []documentEntry
+	newline string
+}
+
+// A documentEntry is either a comment line inside a Paragraph, or a field.
+type documentEntry struct {
+	comment string
+	field   *documentField
+}
+
+// A documentField is the raw text of a single field, the Key line along
+// with all continuation lines (and comments between them).
+type documentField struct {
+	key string
+	raw string
+}
+
+// ParseDocument {{{
+
+// Read a deb822 Document from the given `io.Reader`. OpenPGP signatures
+// are not handled by the Document, and will be rejected as garbage lines.
+func ParseDocument(reader io.Reader) (*Document, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := Document{newline: "\n"}
+	lines := splitLinesKeepEnds(string(data))
+	if len(lines) > 0 && strings.HasSuffix(lines[0], "\r\n") {
+		doc.newline = "\r\n"
+	}
+
+	var (
+		current  *DocumentParagraph
+		pending  []string
+		lastItem *documentField
+	)
+
+	for lineNo, line := range lines {
+		switch {
+		case isBlankLine([]byte(line)) || (lastItem == nil && strings.TrimSpace(line) == ""):
+			/* Blank line; this ends the current Paragraph, if any. */
+			if current != nil {
+				for _, comment := range pending {
+					current.entries = append(current.entries, documentEntry{comment: comment})
+				}
+				pending = nil
+			}
+			current = nil
+			lastItem = nil
+			pending = append(pending, line)
+		case strings.HasPrefix(line, "#"):
+			pending = append(pending, line)
+		case strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t"):
+			if lastItem == nil {
+				return nil, &ParseError{
+					Line:    lineNo + 1,
+					Column:  1,
+					Message: fmt.Sprintf("Bad line: '%s' continues no field", strings.TrimRight(line, "\r\n")),
+				}
+			}
+			/* Comments between continuation lines belong to the field */
+			lastItem.raw += strings.Join(pending, "") + line
+			pending = nil
+		default:
+			colon := strings.Index(line, ":")
+			if colon == -1 {
+				return nil, &ParseError{
+					Line:    lineNo + 1,
+					Column:  1,
+					Message: fmt.Sprintf("Bad line: '%s' has no ':'", strings.TrimRight(line, "\r\n")),
+				}
+			}
+			if current == nil {
+				current = &DocumentParagraph{leading: pending, newline: doc.newline}
+				doc.Paragraphs = append(doc.Paragraphs, current)
+			} else {
+				for _, comment := range pending {
+					current.entries = append(current.entries, documentEntry{comment: comment})
+				}
+			}
+			pending = nil
+			lastItem = &documentField{
+				key: strings.TrimSpace(line[:colon]),
+				raw: line,
+			}
+			current.entries = append(current.entries, documentEntry{field: lastItem})
+		}
+	}
+
+	if current != nil {
+		for _, comment := range pending {
+			current.entries = append(current.entries, documentEntry{comment: comment})
+		}
+		pending = nil
+	}
+	doc.trailer = pending
+
+	return &doc, nil
+}
+
+// Split a string into lines, keeping the line endings, so that joining the
+// lines back together results in the original string.
+func splitLinesKeepEnds(data string) []string {
+	ret := []string{}
+	for data != "" {
+		i := strings.IndexByte(data, '\n')
+		if i == -1 {
+			ret = append(ret, data)
+			break
+		}
+		ret = append(ret, data[:i+1])
+		data = data[i+1:]
+	}
+	return ret
+}
+
+// }}}
+
+// Document Helpers {{{
+
+// Append a new, empty, Paragraph to the end of the Document, and return it.
+// Any comments after the last Paragraph are kept before the new one.
+func (d *Document) AddParagraph() *DocumentParagraph {
+	para := &DocumentParagraph{newline: d.newline, leading: d.trailer}
+	d.trailer = nil
+
+	if len(d.Paragraphs) > 0 {
+		last := d.Paragraphs[len(d.Paragraphs)-1]
+		last.terminate()
+
+		separated := false
+		for _, line := range para.leading {
+			separated = separated || strings.TrimSpace(line) == ""
+		}
+		if !separated {
+			para.leading = append([]string{d.newline}, para.leading...)
+		}
+	}
+
+	d.Paragraphs = append(d.Paragraphs, para)
+	return para
+}
+
+// Write the Document out to the given `io.Writer`.
+func (d *Document) WriteTo(out io.Writer) (int64, error) {
+	var written int64
+	write := func(data string) error {
+		n, err := io.WriteString(out, data)
+		written += int64(n)
+		return err
+	}
+
+	for _, para := range d.Paragraphs {
+		for _, line := range para.leading {
+			if err := write(line); err != nil {
+				return written, err
+			}
+		}
+		for _, entry := range para.entries {
+			data := entry.comment
+			if entry.field != nil {
+				data = entry.field.raw
+			}
+			if err := write(data); err != nil {
+				return written, err
+			}
+		}
+	}
+	for _, line := range d.trailer {
+		if err := write(line); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Return the Document as a string, exactly as WriteTo would write it.
+func (d *Document) String() string {
+	out := strings.Builder{}
+	d.WriteTo(&out)
+	return out.String()
+}
+
+// }}}
+
+// DocumentParagraph Helpers {{{
+
+func (p *DocumentParagraph) find(key string) *documentField {
+	for _, entry := range p.entries {
+		if entry.field != nil && strings.EqualFold(entry.field.key, key) {
+			return entry.field
+		}
+	}
+	return nil
+}
+
+// Return the Keys in this Paragraph, in the order they appear.
+func (p *DocumentParagraph) Keys() []string {
+	ret := []string{}
+	for _, entry := range p.entries {
+		if entry.field != nil {
+			ret = append(ret, entry.field.key)
+		}
+	}
+	return ret
+}
+
+// Return the value of the given Key (compared case-insensitively), parsed
+// the same way the ParagraphReader would have parsed it, and if the Key was
+// found at all.
+func (p *DocumentParagraph) Get(key string) (string, bool) {
+	field := p.find(key)
+	if field == nil {
+		return "", false
+	}
+	return field.value(), true
+}
+
+// Set the value of the given Key. If the Key is already present (compared
+// case-insensitively), the field is re-formatted in place, keeping the
+// original spelling of the Key. Otherwise, the field is added to the end of
+// the Paragraph.
+func (p *DocumentParagraph) Set(key, value string) {
+	if field := p.find(key); field != nil {
+		_, first := partitionRaw(field.raw)
+		field.raw = formatField(field.key, value, strings.TrimSpace(first) == "", p.newline)
+		return
+	}
+	p.terminate()
+	p.entries = append(p.entries, documentEntry{field: &documentField{
+		key: key,
+		raw: formatField(key, value, false, p.newline),
+	}})
+}
+
+// Make sure the last line of the Paragraph ends with a newline, so that
+// anything written after it starts on a line of its own.
+func (p *DocumentParagraph) terminate() {
+	if len(p.entries) == 0 {
+		return
+	}
+	last := &p.entries[len(p.entries)-1]
+	if last.field != nil && !strings.HasSuffix(last.field.raw, "\n") {
+		last.field.raw += p.newline
+	} else if last.field == nil && !strings.HasSuffix(last.comment, "\n") {
+		last.comment += p.newline
+	}
+}
+
+// Remove the given Key from the Paragraph, returning true if the Key was
+// present.
+func (p *DocumentParagraph) Delete(key string) bool {
+	for i, entry := range p.entries {
+		if entry.field != nil && strings.EqualFold(entry.field.key, key) {
+			p.entries = append(p.entries[:i], p.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Return the parsed Paragraph, as the ParagraphReader would have returned it.
+func (p *DocumentParagraph) Paragraph() Paragraph {
+	ret := Paragraph{Order: []string{}, Values: map[string]string{}}
+	for _, entry := range p.entries {
+		if entry.field != nil {
+			ret.Order = append(ret.Order, entry.field.key)
+			ret.Values[entry.field.key] = entry.field.value()
+		}
+	}
+	return ret
+}
+
+// Parse the raw text of this field, using the ParagraphReader, so that the
+// value is exactly what it would have been had we read it that way.
+func (f *documentField) value() string {
+	reader, err := NewParagraphReader(strings.NewReader(f.raw), nil)
+	if err != nil {
+		return ""
+	}
+	para, err := reader.Next()
+	if err != nil {
+		return ""
+	}
+	return para.Values[f.key]
+}
+
+// Split the raw text of a field into the Key and the rest of the first line.
+func partitionRaw(raw string) (string, string) {
+	line := raw
+	if i := strings.IndexByte(raw, '\n'); i != -1 {
+		line = raw[:i]
+	}
+	colon := strings.Index(line, ":")
+	if colon == -1 {
+		return line, ""
+	}
+	return line[:colon], line[colon+1:]
+}
+
+// Format a field for output, folding the value the same way Paragraph.WriteTo
+// would. If `emptyFirst` is set, and the value spans multiple lines, the
+// first line of the value is put on a continuation line, as is commonly done
+// for Build-Depends and friends.
+func formatField(key, value string, emptyFirst bool, newline string) string {
+	value = strings.TrimRight(value, "\n")
+	lines := strings.Split(value, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "" {
+			lines[i] = "."
+		}
+	}
+
+	if emptyFirst && len(lines) > 1 && lines[0] != "" {
+		lines = append([]string{""}, lines...)
+	}
+
+	out := strings.Builder{}
+	out.WriteString(key)
+	out.WriteString(":")
+	if lines[0] != "" {
+		out.WriteString(" ")
+		out.WriteString(lines[0])
+	}
+	out.WriteString(newline)
+	for _, line := range lines[1:] {
+		out.WriteString(" ")
+		out.WriteString(line)
+		out.WriteString(newline)
+	}
+	return out.String()
+}
+
+// }}}
+
+// }}}
+
+// vim: foldmethod=marker
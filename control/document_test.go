@@ -0,0 +1,114 @@
+package control_test
+
+import (
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+const testDocument = `# Top of the file comment
+
+Source: hello
+Section: devel
+Priority:   optional
+Maintainer: Santiago Vila <sanvila@debian.org>
+Build-Depends:
+ debhelper-compat (= 13),
+# a comment inside a field
+   libfoo-dev,
+Standards-Version: 4.6.0
+Rules-Requires-Root: no
+
+
+# Comment before a binary
+Package: hello
+Architecture: any
+Depends: ${shlibs:Depends}, ${misc:Depends}
+Description: example package based on GNU hello
+ The GNU hello program produces a familiar, friendly greeting.
+ .
+ It is a fun package.
+# trailing comment in the paragraph
+
+# last comment, no newline`
+
+func TestDocumentRoundTrip(t *testing.T) {
+	doc, err := control.ParseDocument(strings.NewReader(testDocument))
+	isok(t, err)
+	assert(t, doc.String() == testDocument)
+	assert(t, len(doc.Paragraphs) == 2)
+
+	source := doc.Paragraphs[0]
+	assert(t, len(source.Keys()) == 7)
+	priority, ok := source.Get("Priority")
+	assert(t, ok)
+	assert(t, priority == "optional")
+
+	buildDepends, ok := source.Get("Build-Depends")
+	assert(t, ok)
+	assert(t, buildDepends == "debhelper-compat (= 13),\n  libfoo-dev,\n")
+
+	_, ok = source.Get("Homepage")
+	assert(t, !ok)
+
+	binary := doc.Paragraphs[1].Paragraph()
+	assert(t, binary.Values["Description"] == `example package based on GNU hello
+The GNU hello program produces a familiar, friendly greeting.
+
+It is a fun package.
+`)
+}
+
+func TestDocumentSet(t *testing.T) {
+	doc, err := control.ParseDocument(strings.NewReader(testDocument))
+	isok(t, err)
+
+	source := doc.Paragraphs[0]
+	source.Set("Standards-Version", "4.7.0")
+	assert(t, doc.String() == strings.Replace(testDocument, "4.6.0", "4.7.0", 1))
+
+	source.Set("Build-Depends", "debhelper-compat (= 13),\nlibbar-dev,")
+	assert(t, strings.Contains(doc.String(), `Build-Depends:
+ debhelper-compat (= 13),
+ libbar-dev,
+Standards-Version: 4.7.0
+`))
+
+	binary := doc.Paragraphs[1]
+	binary.Set("Multi-Arch", "foreign")
+	assert(t, strings.Contains(doc.String(), `# trailing comment in the paragraph
+Multi-Arch: foreign
+
+# last comment`))
+
+	assert(t, binary.Delete("Depends"))
+	assert(t, !binary.Delete("Depends"))
+	assert(t, !strings.Contains(doc.String(), "shlibs"))
+}
+
+func TestDocumentAddParagraph(t *testing.T) {
+	doc, err := control.ParseDocument(strings.NewReader("Source: hello\nSection: devel"))
+	isok(t, err)
+
+	para := doc.AddParagraph()
+	para.Set("Package", "hello")
+	para.Set("Description", "greeting\nA long description.\n\nWith paragraphs.")
+	assert(t, doc.String() == `Source: hello
+Section: devel
+
+Package: hello
+Description: greeting
+ A long description.
+ .
+ With paragraphs.
+`)
+}
+
+func TestDocumentBadLine(t *testing.T) {
+	_, err := control.ParseDocument(strings.NewReader("Source: hello\nGarbage\n"))
+	notok(t, err)
+
+	_, err = control.ParseDocument(strings.NewReader("\n continuation\n"))
+	notok(t, err)
+}
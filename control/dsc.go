@@ -45,31 +45,39 @@ import (
 type DSC struct {
 	Paragraph
 
-	Filename string
+	Filename string `control:"-"`
 
 	Format           string
 	Source           string
-	Binaries         []string          `control:"Binary" delim:","`
+	Binaries         []string          `control:"Binary" delim:"," strip:"\n\r\t " join:", "`
 	Architectures    []dependency.Arch `control:"Architecture"`
 	Version          version.Version
 	Origin           string
-	Maintainer       string
-	Uploaders        []string
+	Maintainer       Person
+	Uploaders        People
 	Homepage         string
 	StandardsVersion string `control:"Standards-Version"`
 
+	VcsFields
+	Testsuite         []string `delim:"," strip:"\n\r\t " join:", "`
+	TestsuiteTriggers []string `control:"Testsuite-Triggers" delim:"," strip:"\n\r\t " join:", "`
+
 	BuildDepends      dependency.Dependency `control:"Build-Depends"`
 	BuildDependsArch  dependency.Dependency `control:"Build-Depends-Arch"`
 	BuildDependsIndep dependency.Dependency `control:"Build-Depends-Indep"`
 
-	ChecksumsSha1   []SHA1FileHash   `control:"Checksums-Sha1" delim:"\n" strip:"\n\r\t "`
-	ChecksumsSha256 []SHA256FileHash `control:"Checksums-Sha256" delim:"\n" strip:"\n\r\t "`
-	Files           []MD5FileHash    `control:"Files" delim:"\n" strip:"\n\r\t "`
+	BuildConflicts      dependency.Dependency `control:"Build-Conflicts"`
+	BuildConflictsArch  dependency.Dependency `control:"Build-Conflicts-Arch"`
+	BuildConflictsIndep dependency.Dependency `control:"Build-Conflicts-Indep"`
 
-	/*
-		TODO:
-			Package-List
-	*/
+	PackageList PackageList `control:"Package-List" delim:"\n" strip:"\n\r\t " multiline:"true"`
+
+	ChecksumsSha1   []SHA1FileHash   `control:"Checksums-Sha1" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	ChecksumsSha256 []SHA256FileHash `control:"Checksums-Sha256" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	ChecksumsSha512 []SHA512FileHash `control:"Checksums-Sha512" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	Files           []MD5FileHash    `control:"Files" delim:"\n" strip:"\n\r\t " multiline:"true"`
+
+	Dgit Dgit
 }
 
 // Given a bunch of DSC objects, sort the packages topologically by
@@ -169,8 +177,8 @@ func (d *DSC) HasArchAll() bool {
 // Return a list of all entities that are responsible for the package's
 // well being. The 0th element is always the package's Maintainer,
 // with any Uploaders following.
-func (d *DSC) Maintainers() []string {
-	return append([]string{d.Maintainer}, d.Uploaders...)
+func (d *DSC) Maintainers() []Person {
+	return append([]Person{d.Maintainer}, d.Uploaders...)
 }
 
 // Return a list of MD5FileHash entries from the `dsc.Files`
@@ -188,6 +196,28 @@ func (d *DSC) AbsFiles() []MD5FileHash {
 	return ret
 }
 
+// Verify the files referenced by the .dsc, which are expected to be next to
+// it on the filesystem. The Checksums-* fields are checked against the Files
+// field, and then every file is read once and checked against all of its
+// checksums. If anything is wrong, the returned error is a *VerifyError
+// listing each problem.
+func (d *DSC) Verify() error {
+	fields := [][]FileHash{{}, {}, {}, {}}
+	for _, hash := range d.Files {
+		fields[0] = append(fields[0], hash.FileHash)
+	}
+	for _, hash := range d.ChecksumsSha1 {
+		fields[1] = append(fields[1], hash.FileHash)
+	}
+	for _, hash := range d.ChecksumsSha256 {
+		fields[2] = append(fields[2], hash.FileHash)
+	}
+	for _, hash := range d.ChecksumsSha512 {
+		fields[3] = append(fields[3], hash.FileHash)
+	}
+	return verifyFiles(filepath.Dir(d.Filename), fields...)
+}
+
 // Copy the .dsc file and all referenced files to the directory
 // listed by the dest argument. This function will error out if the dest
 // argument is not a directory, or if there is an IO operation in transfer.
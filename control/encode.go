@@ -26,6 +26,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Marshallable {{{
@@ -58,76 +59,97 @@ func ConvertToParagraph(incoming interface{}) (*Paragraph, error) {
 // Top-level conversion dispatch {{{
 
 func convertToParagraph(data reflect.Value) (*Paragraph, error) {
-	order := []string{}
-	values := map[string]string{}
-
 	if data.Type().Kind() != reflect.Struct {
 		return nil, fmt.Errorf("Can only Decode a Struct")
 	}
 
-	paragraphType := reflect.TypeOf(Paragraph{})
+	fields := Paragraph{Order: []string{}, Values: map[string]string{}}
 	var foundParagraph Paragraph = Paragraph{}
 
-	for i := 0; i < data.NumField(); i++ {
-		field := data.Field(i)
-		fieldType := data.Type().Field(i)
+	if err := convertStructToParagraph(data, &fields, &foundParagraph); err != nil {
+		return nil, err
+	}
+
+	para := foundParagraph.Update(fields)
+	return &para, nil
+}
+
+// Walk the Struct, adding each field to `para`, and flattening inline
+// Structs into it as we go. If we find a control.Paragraph Anonymous member,
+// it's put into `found`.
+func convertStructToParagraph(data reflect.Value, para *Paragraph, found *Paragraph) error {
+	for _, fieldPlan := range planFor(data.Type()).fields {
+		field := data.Field(fieldPlan.index)
 
-		if fieldType.Anonymous {
-			if fieldType.Type == paragraphType {
-				foundParagraph = field.Interface().(Paragraph)
+		if fieldPlan.paragraph {
+			*found = field.Interface().(Paragraph)
+			continue
+		}
+
+		if fieldPlan.inline {
+			if err := convertStructToParagraph(field, para, found); err != nil {
+				return err
 			}
 			continue
 		}
 
-		paragraphKey := fieldType.Name
-		if it := fieldType.Tag.Get("control"); it != "" {
-			paragraphKey = it
+		if !fieldPlan.value {
+			continue
 		}
 
-		if paragraphKey == "-" {
-			/* If the key is "-", lets go ahead and skip it */
+		if fieldPlan.omitEmpty && field.IsZero() {
 			continue
 		}
 
-		data, err := marshalStructValue(field, fieldType)
+		value, err := marshalStructValue(field, fieldPlan)
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		required := fieldType.Tag.Get("required") == "true"
-		if data == "" && !required {
-			continue
+		if value == "" {
+			if fieldPlan.hasDefault {
+				value = fieldPlan.def
+			} else if !fieldPlan.required {
+				continue
+			}
 		}
 
-		if fieldType.Tag.Get("multiline") == "true" {
-			data = "\n" + data
+		if fieldPlan.multiline {
+			value = "\n" + value
 		}
 
-		order = append(order, paragraphKey)
-		values[paragraphKey] = data
+		para.Order = append(para.Order, fieldPlan.key)
+		para.Values[fieldPlan.key] = value
 	}
-	para := foundParagraph.Update(Paragraph{Order: order, Values: values})
-	return &para, nil
+	return nil
 }
 
 // }}}
 
 // convert a struct value {{{
 
-func marshalStructValue(field reflect.Value, fieldType reflect.StructField) (string, error) {
+func marshalStructValue(field reflect.Value, fieldPlan fieldPlan) (string, error) {
 	switch field.Type().Kind() {
 	case reflect.String:
 		return field.String(), nil
-	case reflect.Uint:
-		return strconv.Itoa(int(field.Uint())), nil
-	case reflect.Int:
-		return strconv.Itoa(int(field.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(field.Int(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(field.Float(), 'f', -1, field.Type().Bits()), nil
 	case reflect.Ptr:
-		return marshalStructValue(field.Elem(), fieldType)
+		if field.IsNil() {
+			return "", nil
+		}
+		return marshalStructValue(field.Elem(), fieldPlan)
 	case reflect.Slice:
-		return marshalStructValueSlice(field, fieldType)
+		if marshal, ok := field.Interface().(Marshallable); ok {
+			return marshal.MarshalControl()
+		}
+		return marshalStructValueSlice(field, fieldPlan)
 	case reflect.Struct:
-		return marshalStructValueStruct(field, fieldType)
+		return marshalStructValueStruct(field)
 	case reflect.Bool:
 		if field.Bool() {
 			return "yes", nil
@@ -141,7 +163,11 @@ func marshalStructValue(field reflect.Value, fieldType reflect.StructField) (str
 
 // convert a struct value of type struct {{{
 
-func marshalStructValueStruct(field reflect.Value, fieldType reflect.StructField) (string, error) {
+func marshalStructValueStruct(field reflect.Value) (string, error) {
+	if field.Type() == timeType {
+		return formatTime(field.Interface().(time.Time)), nil
+	}
+
 	/* Right, so, we've got a type we don't know what to do with. We should
 	 * grab the method, or throw a shitfit. */
 	if marshal, ok := field.Interface().(Marshallable); ok {
@@ -158,23 +184,19 @@ func marshalStructValueStruct(field reflect.Value, fieldType reflect.StructField
 
 // convert a struct value of type slice {{{
 
-func marshalStructValueSlice(field reflect.Value, fieldType reflect.StructField) (string, error) {
-	var delim = " "
-	if it := fieldType.Tag.Get("delim"); it != "" {
-		delim = it
-	}
+func marshalStructValueSlice(field reflect.Value, fieldPlan fieldPlan) (string, error) {
 	data := []string{}
 
 	for i := 0; i < field.Len(); i++ {
 		elem := field.Index(i)
-		if stringification, err := marshalStructValue(elem, fieldType); err != nil {
+		if stringification, err := marshalStructValue(elem, fieldPlan); err != nil {
 			return "", err
 		} else {
 			data = append(data, stringification)
 		}
 	}
 
-	return strings.Join(data, delim), nil
+	return strings.Join(data, fieldPlan.join), nil
 }
 
 // }}}
@@ -204,7 +226,20 @@ func marshalStructValueSlice(field reflect.Value, fieldType reflect.StructField)
 // `control:""`.
 //
 // If you're dehydrating a list of strings, you have the option of defining
-// a string to join the tokens with (`delim:", "`).
+// a string to join the tokens with (`delim:", "`). If the tokens should be
+// joined with something other than the delimiter they're split on, such as
+// a comma followed by a space, that can be set with `join:", "`.
+//
+// Fields that marshal to an empty string are left out, unless they're
+// tagged `required:"true"`, or have a default (`default:"..."`), in which
+// case the default is written instead. Use `default:""` to write a field
+// with an intentionally empty value. The `omitempty` option
+// (`control:"Key,omitempty"`) will also leave out fields holding the zero
+// value for their type, such as 0 or false.
+//
+// Anonymous Struct members, and Struct members with the `inline` option
+// (`control:",inline"`), have their fields written into the same Paragraph,
+// rather than being marshaled as a single value.
 //
 // In order to Marshal a custom Struct, you are required to implement the
 // Marshallable interface. It's highly encouraged to put this interface on
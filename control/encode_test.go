@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"strings"
 	"testing"
+	"time"
 
 	"pault.ag/go/debian/control"
 	"pault.ag/go/debian/dependency"
@@ -42,6 +43,22 @@ X-A-Test: Foo
 `)
 }
 
+func TestCaseInsensitiveMarshal(t *testing.T) {
+	el := TestParaMarshalStruct{}
+
+	isok(t, control.Unmarshal(&el, strings.NewReader(`foo: test
+X-A-Test: Foo
+`)))
+	assert(t, el.Foo == "test")
+
+	el.Foo = "changed"
+	writer := bytes.Buffer{}
+	isok(t, control.Marshal(&writer, el))
+	assert(t, writer.String() == `foo: changed
+X-A-Test: Foo
+`)
+}
+
 func TestBasicMarshal(t *testing.T) {
 	testStruct := TestMarshalStruct{Foo: "Hello"}
 
@@ -136,4 +153,150 @@ func TestBoolMarshal(t *testing.T) {
 `)
 }
 
+type richMarshalStruct struct {
+	Size      int64
+	Count     uint64
+	Ratio     float32
+	Date      time.Time
+	Local     time.Time
+	Priority  *int
+	Pin       *int
+	Essential bool
+}
+
+func TestRichTypesMarshal(t *testing.T) {
+	priority := 990
+	rs := richMarshalStruct{
+		Size:     -1,
+		Count:    18446744073709551615,
+		Ratio:    0.5,
+		Date:     time.Date(2021, 8, 14, 7, 51, 3, 0, time.UTC),
+		Local:    time.Date(2015, 11, 16, 21, 15, 55, 0, time.FixedZone("", -8*60*60)),
+		Priority: &priority,
+	}
+
+	writer := bytes.Buffer{}
+	isok(t, control.Marshal(&writer, rs))
+	assert(t, writer.String() == `Size: -1
+Count: 18446744073709551615
+Ratio: 0.5
+Date: Sat, 14 Aug 2021 07:51:03 UTC
+Local: Mon, 16 Nov 2015 21:15:55 -0800
+Priority: 990
+Essential: no
+`)
+
+	back := richMarshalStruct{}
+	isok(t, control.Unmarshal(&back, &writer))
+	assert(t, back.Date.Equal(rs.Date))
+	assert(t, back.Local.Equal(rs.Local))
+	assert(t, *back.Priority == 990)
+	assert(t, back.Pin == nil)
+}
+
+type Checksums struct {
+	control.BestChecksums `control:",inline"`
+}
+
+type composedStruct struct {
+	Package   string
+	Essential bool      `control:",omitempty"`
+	Size      int       `control:"Installed-Size,omitempty"`
+	Priority  string    `default:"optional"`
+	Empty     string    `control:"Rules-Requires-Root" default:""`
+	Hashes    Checksums `control:",inline"`
+	Extra     struct {
+		Homepage string
+	} `control:",inline"`
+}
+
+func TestTagOptionsMarshal(t *testing.T) {
+	cs := composedStruct{Package: "hello"}
+
+	writer := bytes.Buffer{}
+	isok(t, control.Marshal(&writer, cs))
+	assert(t, writer.String() == `Package: hello
+Priority: optional
+Rules-Requires-Root:
+`)
+
+	cs.Essential = true
+	cs.Size = 12
+	cs.Priority = "required"
+	cs.Extra.Homepage = "https://www.gnu.org/software/hello/"
+	isok(t, control.Unmarshal(&cs.Hashes, strings.NewReader(`Checksums-Sha256:
+ 0a4f8cc793903e366a84379a651bf1a4542d50823b4bd4e038efcdb85a1af95e 1818 hello_1.0-1.dsc
+`)))
+	assert(t, len(cs.Hashes.Checksums()) == 1)
+
+	writer = bytes.Buffer{}
+	isok(t, control.Marshal(&writer, cs))
+	assert(t, writer.String() == `Package: hello
+Essential: yes
+Installed-Size: 12
+Priority: required
+Rules-Requires-Root:
+Checksums-Sha256:
+ 0a4f8cc793903e366a84379a651bf1a4542d50823b4bd4e038efcdb85a1af95e 1818 hello_1.0-1.dsc
+Homepage: https://www.gnu.org/software/hello/
+`)
+}
+
+func TestTagOptionsUnmarshal(t *testing.T) {
+	cs := composedStruct{}
+	isok(t, control.Unmarshal(&cs, strings.NewReader(`Package: hello
+Installed-Size: 12
+Homepage: https://www.gnu.org/software/hello/
+Checksums-Sha256:
+ 0a4f8cc793903e366a84379a651bf1a4542d50823b4bd4e038efcdb85a1af95e 1818 hello_1.0-1.dsc
+`)))
+	assert(t, cs.Package == "hello")
+	assert(t, cs.Size == 12)
+	assert(t, cs.Priority == "optional")
+	assert(t, cs.Extra.Homepage == "https://www.gnu.org/software/hello/")
+	assert(t, len(cs.Hashes.Checksums()) == 1)
+}
+
+func TestParagraphWriteTo(t *testing.T) {
+	para := control.Paragraph{
+		Order: []string{"Package", "Description", "Conffiles", "Empty"},
+		Values: map[string]string{
+			"Package":     "hello",
+			"Description": "example package\n more text",
+			"Conffiles":   "\n/etc/hello.conf c95db2aeebde025e0fa7f60a25587efe",
+			"Empty":       "",
+		},
+	}
+	writer := bytes.Buffer{}
+	isok(t, para.WriteTo(&writer))
+	assert(t, writer.String() == `Package: hello
+Description: example package
+  more text
+Conffiles:
+ /etc/hello.conf c95db2aeebde025e0fa7f60a25587efe
+Empty:
+`)
+
+	/* Everything is still in the one Paragraph when read back in */
+	reader, err := control.NewParagraphReader(&writer, nil)
+	isok(t, err)
+	again, err := reader.Next()
+	isok(t, err)
+	assert(t, len(again.Order) == 4)
+	conffiles, _ := again.Get("Conffiles")
+	assert(t, strings.TrimSpace(conffiles) == "/etc/hello.conf c95db2aeebde025e0fa7f60a25587efe")
+}
+
+func TestParagraphDelete(t *testing.T) {
+	para := control.Paragraph{
+		Order:  []string{"Package", "Version"},
+		Values: map[string]string{"Package": "hello", "Version": "1.0"},
+	}
+	assert(t, para.Delete("version"))
+	assert(t, !para.Delete("Version"))
+	assert(t, len(para.Order) == 1 && para.Order[0] == "Package")
+	_, ok := para.Get("Version")
+	assert(t, !ok)
+}
+
 // vim: foldmethod=marker
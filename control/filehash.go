@@ -40,11 +40,11 @@ import (
 // Checksum-Sha256 entry for the .dsc or .changes files.
 type FileHash struct {
 	// cb136f28a8c971d4299cc68e8fdad93a8ca7daf3 1131 dput-ng_1.9.dsc
-	Algorithm string
-	Hash      string
-	Size      int64
-	Filename  string
-	ByHash    string
+	Algorithm string `json:"algorithm"`
+	Hash      string `json:"hash"`
+	Size      int64  `json:"size"`
+	Filename  string `json:"filename"`
+	ByHash    string `json:"byHash,omitempty"`
 }
 
 func FileHashFromHasher(path string, hasher hashio.Hasher) FileHash {
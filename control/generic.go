@@ -0,0 +1,135 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+)
+
+// Typed decoding {{{
+
+// Return the plan for T, which must be a Struct.
+func typedPlan[T any]() (*structPlan, error) {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Can't Decode into a %s", t)
+	}
+	return planFor(t), nil
+}
+
+// Iterate {{{
+
+// Iterate returns an iterator over the Paragraphs of the control stream
+// read from `reader`, each unpacked into a T, following the same rules as
+// the Unmarshal API. T must be a Struct type.
+//
+// If an error is encountered (including an OpenPGP error while setting up
+// the stream), it is yielded along with the zero value of T, and iteration
+// stops.
+//
+//	for pkg, err := range control.Iterate[control.BinaryIndex](reader) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(pkg.Package)
+//	}
+func Iterate[T any](reader io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		plan, err := typedPlan[T]()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		paragraphs, err := NewParagraphReader(reader, nil)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		for {
+			paragraph, err := paragraphs.Next()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			var ret T
+			if err := decodeStructPlan(*paragraph, reflect.ValueOf(&ret).Elem(), plan); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(ret, nil) {
+				return
+			}
+		}
+	}
+}
+
+// }}}
+
+// DecodeAll {{{
+
+// DecodeAll reads every Paragraph of the control stream read from `reader`,
+// unpacking each into a T, following the same rules as the Unmarshal API.
+// T must be a Struct type.
+//
+//	packages, err := control.DecodeAll[control.BinaryIndex](reader)
+func DecodeAll[T any](reader io.Reader) ([]T, error) {
+	ret := []T{}
+	for it, err := range Iterate[T](reader) {
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, it)
+	}
+	return ret, nil
+}
+
+// }}}
+
+// DecodeOne {{{
+
+// DecodeOne reads the first Paragraph of the control stream read from
+// `reader` into a T, which is handy for single Paragraph files, such as
+// .dsc and .changes files. T must be a Struct type.
+//
+//	dsc, err := control.DecodeOne[control.DSC](reader)
+func DecodeOne[T any](reader io.Reader) (T, error) {
+	for it, err := range Iterate[T](reader) {
+		return it, err
+	}
+	var zero T
+	return zero, io.EOF
+}
+
+// }}}
+
+// }}}
+
+// vim: foldmethod=marker
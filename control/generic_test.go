@@ -0,0 +1,74 @@
+package control_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+func TestDecodeAll(t *testing.T) {
+	items, err := control.DecodeAll[TestStruct](strings.NewReader(`Value: foo
+Foo-Bar: baz
+
+Value: Bar
+
+Value: Baz
+`))
+	isok(t, err)
+	assert(t, len(items) == 3)
+	assert(t, items[0].Value == "foo")
+	assert(t, items[2].Value == "Baz")
+
+	_, err = control.DecodeAll[TestStruct](strings.NewReader(`Value: foo
+
+Foo-Bar: baz
+`))
+	notok(t, err)
+
+	_, err = control.DecodeAll[string](strings.NewReader("Value: foo\n"))
+	notok(t, err)
+}
+
+// Return a reader for a Packages index long enough to stop iterating
+// part way through.
+func iterateIndexReader() io.Reader {
+	return bytes.NewReader(benchmarkBinaryIndexData())
+}
+
+func TestIterate(t *testing.T) {
+	packages := []string{}
+	for pkg, err := range control.Iterate[control.BinaryIndex](iterateIndexReader()) {
+		isok(t, err)
+		packages = append(packages, pkg.Package)
+		if len(packages) == 10 {
+			break
+		}
+	}
+	assert(t, len(packages) == 10)
+	assert(t, packages[9] == "android-tools-fsutils")
+
+	count := 0
+	for _, err := range control.Iterate[TestStruct](strings.NewReader("Value: one\n\nGarbage\n")) {
+		count++
+		if count == 1 {
+			isok(t, err)
+		} else {
+			notok(t, err)
+		}
+	}
+	assert(t, count == 2)
+}
+
+func TestDecodeOne(t *testing.T) {
+	changes, err := control.DecodeOne[control.Changes](strings.NewReader(signedParagraph))
+	isok(t, err)
+	assert(t, changes.Source == "hy")
+	assert(t, changes.Version.String() == "0.11.0-4")
+	assert(t, len(changes.Files) == 2)
+
+	_, err = control.DecodeOne[control.DSC](strings.NewReader(""))
+	assert(t, err == io.EOF)
+}
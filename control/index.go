@@ -22,6 +22,8 @@ package control // import "pault.ag/go/debian/control"
 
 import (
 	"bufio"
+	"io"
+	"reflect"
 	"strings"
 
 	"pault.ag/go/debian/dependency"
@@ -41,7 +43,7 @@ type BinaryIndex struct {
 	Package        string
 	Source         string
 	Version        version.Version
-	InstalledSize  int     `control:"Installed-Size"`
+	InstalledSize  int `control:"Installed-Size"`
 	Maintainer     string
 	Architecture   dependency.Arch
 	MultiArch      string `control:"Multi-Arch"`
@@ -116,8 +118,8 @@ func (index *BinaryIndex) SourcePackage() string {
 // The struct fields of BestChecksums need to be exported for the unmarshaling
 // process but most not be used directly. Use the Checksums() accessor instead.
 type BestChecksums struct {
-	ChecksumsSha256 []SHA256FileHash `control:"Checksums-Sha256" delim:"\n" strip:"\n\r\t "`
-	ChecksumsSha512 []SHA256FileHash `control:"Checksums-Sha512" delim:"\n" strip:"\n\r\t "`
+	ChecksumsSha256 []SHA256FileHash `control:"Checksums-Sha256" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	ChecksumsSha512 []SHA512FileHash `control:"Checksums-Sha512" delim:"\n" strip:"\n\r\t " multiline:"true"`
 }
 
 // Checksums returns FileHashes of a cryptographically secure kind.
@@ -155,24 +157,31 @@ type SourceIndex struct {
 	Binaries []string `control:"Binary" delim:","`
 
 	Version    version.Version
-	Maintainer string
-	Uploaders  string `delim:","`
+	Maintainer Person
+	Uploaders  People
 
 	Architecture []dependency.Arch
 
-	StandardsVersion string
-	Format           string
-	Files            []MD5FileHash    `delim:"\n" strip:"\n\r\t "`
-	VcsBrowser       string           `control:"Vcs-Browser"`
-	VcsGit           string           `control:"Vcs-Git"`
-	VcsSvn           string           `control:"Vcs-Svn"`
-	VcsBzr           string           `control:"Vcs-Bzr"`
-	ChecksumsSha1    []SHA1FileHash   `control:"Checksums-Sha1" delim:"\n" strip:"\n\r\t "`
-	ChecksumsSha256  []SHA256FileHash `control:"Checksums-Sha256" delim:"\n" strip:"\n\r\t "`
-	Homepage         string
-	Directory        string
-	Priority         string
-	Section          string
+	StandardsVersion  string `control:"Standards-Version"`
+	Format            string
+	Files             []MD5FileHash    `delim:"\n" strip:"\n\r\t " multiline:"true"`
+	ChecksumsSha1     []SHA1FileHash   `control:"Checksums-Sha1" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	ChecksumsSha256   []SHA256FileHash `control:"Checksums-Sha256" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	ChecksumsSha512   []SHA512FileHash `control:"Checksums-Sha512" delim:"\n" strip:"\n\r\t " multiline:"true"`
+	Homepage          string
+	Directory         string
+	Priority          string
+	Section           string
+	Testsuite         []string `delim:"," strip:"\n\r\t " join:", "`
+	TestsuiteTriggers []string `control:"Testsuite-Triggers" delim:"," strip:"\n\r\t " join:", "`
+
+	BuildConflicts      dependency.Dependency `control:"Build-Conflicts"`
+	BuildConflictsArch  dependency.Dependency `control:"Build-Conflicts-Arch"`
+	BuildConflictsIndep dependency.Dependency `control:"Build-Conflicts-Indep"`
+
+	VcsFields
+
+	PackageList PackageList `control:"Package-List" delim:"\n" strip:"\n\r\t " multiline:"true"`
 }
 
 // Parse the Depends Build-Depends relation on this package.
@@ -190,6 +199,92 @@ func (index *SourceIndex) GetBuildDependsIndep() dependency.Dependency {
 	return index.getOptionalDependencyField("Build-Depends-Indep")
 }
 
+// Streaming Index readers {{{
+
+// indexReader is the shared guts of the BinaryIndexReader and
+// SourceIndexReader; a ParagraphReader and a Paragraph that's reused
+// between calls to Next.
+type indexReader struct {
+	paragraphs *ParagraphReader
+	paragraph  Paragraph
+}
+
+func newIndexReader(reader io.Reader) (*indexReader, error) {
+	paragraphs, err := NewParagraphReader(reader, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &indexReader{paragraphs: paragraphs}, nil
+}
+
+func (r *indexReader) next(into reflect.Value) error {
+	if err := r.paragraphs.nextInto(&r.paragraph); err != nil {
+		return err
+	}
+	into.Elem().Set(reflect.Zero(into.Elem().Type()))
+	return decodeStruct(r.paragraph, into)
+}
+
+// BinaryIndexReader reads BinaryIndex entries off of a Packages stream one
+// at a time, rather than consuming the whole index into memory the way
+// ParseBinaryIndex does.
+//
+// In order to keep allocations down, the BinaryIndex (including the
+// Paragraph member) returned by Next is reused on the following call to
+// Next. Copy anything you need to keep around (and take care to copy the
+// Paragraph's Values and Order, not just the Struct).
+type BinaryIndexReader struct {
+	reader *indexReader
+	index  BinaryIndex
+}
+
+// Create a new BinaryIndexReader from the given `io.Reader`.
+func NewBinaryIndexReader(reader io.Reader) (*BinaryIndexReader, error) {
+	ir, err := newIndexReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryIndexReader{reader: ir}, nil
+}
+
+// Return the next BinaryIndex in the stream, or io.EOF once the stream
+// has been consumed. The returned BinaryIndex is only valid until the next
+// call to Next.
+func (r *BinaryIndexReader) Next() (*BinaryIndex, error) {
+	if err := r.reader.next(reflect.ValueOf(&r.index)); err != nil {
+		return nil, err
+	}
+	return &r.index, nil
+}
+
+// SourceIndexReader reads SourceIndex entries off of a Sources stream one
+// at a time. The same reuse rules as the BinaryIndexReader apply.
+type SourceIndexReader struct {
+	reader *indexReader
+	index  SourceIndex
+}
+
+// Create a new SourceIndexReader from the given `io.Reader`.
+func NewSourceIndexReader(reader io.Reader) (*SourceIndexReader, error) {
+	ir, err := newIndexReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &SourceIndexReader{reader: ir}, nil
+}
+
+// Return the next SourceIndex in the stream, or io.EOF once the stream
+// has been consumed. The returned SourceIndex is only valid until the next
+// call to Next.
+func (r *SourceIndexReader) Next() (*SourceIndex, error) {
+	if err := r.reader.next(reflect.ValueOf(&r.index)); err != nil {
+		return nil, err
+	}
+	return &r.index, nil
+}
+
+// }}}
+
 // Given a reader, parse out a list of BinaryIndex structs.
 func ParseBinaryIndex(reader *bufio.Reader) (ret []BinaryIndex, err error) {
 	ret = []BinaryIndex{}
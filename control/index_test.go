@@ -22,6 +22,8 @@ package control_test
 
 import (
 	"bufio"
+	"bytes"
+	"io"
 	"strings"
 	"testing"
 
@@ -93,8 +95,9 @@ Section: misc
 	assert(t, len(sources) == 2)
 
 	fbautostart := sources[1]
-	assert(t, fbautostart.Maintainer == "Paul Tagliamonte <paultag@ubuntu.com>")
-	assert(t, fbautostart.VcsGit == "git://git.debian.org/collab-maint/fbautostart.git")
+	assert(t, fbautostart.Maintainer.Name == "Paul Tagliamonte")
+	assert(t, fbautostart.VcsGit.URL == "git://git.debian.org/collab-maint/fbautostart.git")
+	assert(t, fbautostart.StandardsVersion == "3.9.3")
 
 	assert(t, len(fbautostart.Files) == 3)
 	assert(t, fbautostart.Files[0].Algorithm == "md5")
@@ -263,4 +266,166 @@ Description-md5: d05e72aec9e53ee776fc0735e135889b
     assert(t, conflicts[0].Version.Operator == ">=")
 }
 
+func TestBinaryIndexReader(t *testing.T) {
+	reader, err := control.NewBinaryIndexReader(strings.NewReader(benchmarkBinaryIndexParagraph + `Package: androidsdk-ddms
+Version: 22.2+git20130830~92d25d6-1
+Architecture: all
+Tags: devel::debugger
+`))
+	isok(t, err)
+
+	first, err := reader.Next()
+	isok(t, err)
+	assert(t, first.Package == "android-tools-fsutils")
+	assert(t, first.Version.String() == "4.2.2+git20130529-5.1")
+	assert(t, first.InstalledSize == 504)
+	assert(t, len(first.Tags) == 3)
+	assert(t, first.Tags[2] == "role::program")
+	assert(t, first.Values["Description"] == `Android ext4 utilities with sparse support
+This package contains the ext4 utilities used by the Android build
+system, with support for sparse images.
+
+It is only useful for Android development.
+`)
+
+	second, err := reader.Next()
+	isok(t, err)
+	assert(t, second.Package == "androidsdk-ddms")
+	assert(t, second.Source == "")
+	assert(t, second.InstalledSize == 0)
+	assert(t, second.Architecture == dependency.All)
+	assert(t, len(second.Tags) == 1)
+	assert(t, len(second.Order) == 4)
+	assert(t, second.Values["Source"] == "")
+
+	_, err = reader.Next()
+	assert(t, err == io.EOF)
+}
+
+func TestSourceIndexFullFields(t *testing.T) {
+	sources, err := control.ParseSourceIndex(bufio.NewReader(strings.NewReader(`Package: hello
+Binary: hello
+Version: 2.10-3
+Maintainer: Santiago Vila <sanvila@debian.org>
+Build-Depends: debhelper-compat (= 13)
+Build-Conflicts: autoconf2.13, automake1.4
+Build-Conflicts-Arch: libfoo-dev
+Build-Conflicts-Indep: texlive
+Testsuite: autopkgtest, autopkgtest-pkg-c
+Vcs-Hg: https://hg.example.org/hello
+Directory: pool/main/h/hello
+`)))
+	isok(t, err)
+	assert(t, len(sources) == 1)
+
+	hello := sources[0]
+	assert(t, len(hello.BuildConflicts.Relations) == 2)
+	assert(t, hello.BuildConflicts.Relations[1].Possibilities[0].Name == "automake1.4")
+	assert(t, hello.BuildConflictsArch.Relations[0].Possibilities[0].Name == "libfoo-dev")
+	assert(t, hello.BuildConflictsIndep.Relations[0].Possibilities[0].Name == "texlive")
+	assert(t, len(hello.Testsuite) == 2 && hello.Testsuite[1] == "autopkgtest-pkg-c")
+	assert(t, hello.VcsHg.URL == "https://hg.example.org/hello")
+}
+
+func TestSourceIndexReader(t *testing.T) {
+	reader, err := control.NewSourceIndexReader(strings.NewReader(`Package: fbautostart
+Binary: fbautostart
+Version: 2.718281828-1
+Files:
+ 9d610c30f96623cff07bd880e5cca12f 1899 fbautostart_2.718281828-1.dsc
+
+Package: hello
+Version: 2.10-2
+`))
+	isok(t, err)
+
+	source, err := reader.Next()
+	isok(t, err)
+	assert(t, source.Package == "fbautostart")
+	assert(t, len(source.Files) == 1)
+
+	source, err = reader.Next()
+	isok(t, err)
+	assert(t, source.Package == "hello")
+	assert(t, len(source.Files) == 0)
+
+	_, err = reader.Next()
+	assert(t, err == io.EOF)
+}
+
+// Benchmarks {{{
+
+const benchmarkBinaryIndexParagraph = `Package: android-tools-fsutils
+Source: android-tools
+Version: 4.2.2+git20130529-5.1
+Installed-Size: 504
+Maintainer: Android tools Maintainer <android-tools-devel@lists.alioth.debian.org>
+Architecture: amd64
+Depends: python:any, libc6 (>= 2.14), libselinux1 (>= 2.0.65), zlib1g (>= 1:1.2.3.4)
+Description: Android ext4 utilities with sparse support
+ This package contains the ext4 utilities used by the Android build
+ system, with support for sparse images.
+ .
+ It is only useful for Android development.
+Homepage: http://developer.android.com/guide/developing/tools/adb.html
+Description-md5: 23135bc652e7b302961741f9bcff8397
+Tags: devel::android, implemented-in::c, role::program
+Section: devel
+Priority: extra
+Filename: pool/main/a/android-tools/android-tools-fsutils_4.2.2+git20130529-5.1_amd64.deb
+Size: 71900
+MD5sum: 996732fc455acdcf4682de4f80a2dc95
+SHA1: 5c2320913cc7cc46305390d8b3a7ef51f0a174ef
+SHA256: 270ad759d1fef9cedf894c42b5f559d7386aa1ec4de4cc3880eb44fe8c53c833
+
+`
+
+func benchmarkBinaryIndexData() []byte {
+	return []byte(strings.Repeat(benchmarkBinaryIndexParagraph, 1000))
+}
+
+func BenchmarkParseBinaryIndex(b *testing.B) {
+	data := benchmarkBinaryIndexData()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index, err := control.ParseBinaryIndex(bufio.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(index) != 1000 {
+			b.Fatalf("got %d paragraphs", len(index))
+		}
+	}
+}
+
+func BenchmarkBinaryIndexReader(b *testing.B) {
+	data := benchmarkBinaryIndexData()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reader, err := control.NewBinaryIndexReader(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		count := 0
+		for {
+			_, err := reader.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+			count++
+		}
+		if count != 1000 {
+			b.Fatalf("got %d paragraphs", count)
+		}
+	}
+}
+
+// }}}
+
 // vim: foldmethod=marker
@@ -0,0 +1,361 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"pault.ag/go/debian/dependency"
+)
+
+// MarshalJSON {{{
+
+// MarshalJSON serializes a Struct (or a pointer to one) as a JSON object,
+// using the same field plan as Marshal. The object keys are the control
+// keys (so `Build-Depends`, not `BuildDepends`), written in the order the
+// fields are declared on the Struct. Each value is encoded with
+// `encoding/json`, so a `dependency.Dependency` is written as a tree of
+// Relations and Possibilities, rather than as a string.
+//
+// Fields holding the zero value for their type are left out. If the Struct
+// has an anonymous `control.Paragraph` member, any keys in it that are not
+// also Struct fields (such as `X-*` keys) are written after the Struct
+// fields as JSON strings, so that they survive a round trip through
+// UnmarshalJSON. Relationship fields (such as Depends) are written as a
+// `dependency.Dependency` tree, even when they're only in the Paragraph.
+func MarshalJSON(data interface{}) ([]byte, error) {
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Can only MarshalJSON a Struct")
+	}
+
+	out := bytes.Buffer{}
+	out.WriteString("{")
+
+	known := map[string]bool{}
+	var found *Paragraph
+	if err := marshalJSONStruct(value, &out, known, &found); err != nil {
+		return nil, err
+	}
+
+	if found != nil {
+		for _, key := range found.Order {
+			if known[strings.ToLower(key)] {
+				continue
+			}
+			value, err := paragraphJSONValue(key, found.Values[key])
+			if err != nil {
+				return nil, fmt.Errorf("Field '%s': %w", key, err)
+			}
+			if err := writeJSONMember(&out, key, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	out.WriteString("}")
+	return out.Bytes(), nil
+}
+
+// Walk the Struct, writing each non-zero field to `out`, and recording the
+// (lowercased) keys we've written in `known`.
+func marshalJSONStruct(data reflect.Value, out *bytes.Buffer, known map[string]bool, found **Paragraph) error {
+	for _, fieldPlan := range planFor(data.Type()).fields {
+		field := data.Field(fieldPlan.index)
+
+		if fieldPlan.paragraph {
+			para := field.Interface().(Paragraph)
+			*found = &para
+			continue
+		}
+
+		if fieldPlan.nested && !fieldPlan.value {
+			if err := marshalJSONStruct(field, out, known, found); err != nil {
+				return err
+			}
+			continue
+		}
+
+		known[strings.ToLower(fieldPlan.key)] = true
+		if field.IsZero() {
+			continue
+		}
+		if err := writeJSONMember(out, fieldPlan.key, field.Interface()); err != nil {
+			return fmt.Errorf("Field '%s': %w", fieldPlan.key, err)
+		}
+	}
+	return nil
+}
+
+func writeJSONMember(out *bytes.Buffer, key string, value interface{}) error {
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if out.Len() > 1 {
+		out.WriteString(",")
+	}
+	out.Write(encodedKey)
+	out.WriteString(":")
+	out.Write(encodedValue)
+	return nil
+}
+
+// }}}
+
+// UnmarshalJSON {{{
+
+// UnmarshalJSON is the inverse of MarshalJSON; it reads a JSON object keyed
+// by control keys into the Struct `into` points to. Keys are compared
+// case-insensitively, the same way Unmarshal compares them.
+//
+// If the Struct has an anonymous `control.Paragraph` member, keys that don't
+// match any Struct field are put into it (and must be JSON strings).
+// Otherwise, they're ignored.
+func UnmarshalJSON(into interface{}, data []byte) error {
+	target := reflect.ValueOf(into)
+	if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Can only UnmarshalJSON into a pointer to a Struct")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil {
+		return err
+	} else if token != json.Delim('{') {
+		return fmt.Errorf("Expected a JSON object, got '%v'", token)
+	}
+
+	/* Read the members by hand, so we can keep the order of any keys that
+	 * end up in the Paragraph. */
+	keys := []string{}
+	members := map[string]json.RawMessage{}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		raw := json.RawMessage{}
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+		if _, ok := members[key]; !ok {
+			keys = append(keys, key)
+		}
+		members[key] = raw
+	}
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+
+	used := map[string]bool{}
+	var found *Paragraph
+	if err := unmarshalJSONStruct(target.Elem(), keys, members, used, &found); err != nil {
+		return err
+	}
+
+	if found == nil {
+		return nil
+	}
+	*found = Paragraph{Order: []string{}, Values: map[string]string{}}
+	for _, key := range keys {
+		if used[key] {
+			continue
+		}
+		value, err := paragraphValueFromJSON(key, members[key])
+		if err != nil {
+			return fmt.Errorf("Field '%s': %w", key, err)
+		}
+		found.Set(key, value)
+	}
+	return nil
+}
+
+func unmarshalJSONStruct(
+	data reflect.Value,
+	keys []string,
+	members map[string]json.RawMessage,
+	used map[string]bool,
+	found **Paragraph,
+) error {
+	for _, fieldPlan := range planFor(data.Type()).fields {
+		field := data.Field(fieldPlan.index)
+
+		if fieldPlan.paragraph {
+			*found = field.Addr().Interface().(*Paragraph)
+			continue
+		}
+
+		if fieldPlan.nested && !fieldPlan.value {
+			if err := unmarshalJSONStruct(field, keys, members, used, found); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := fieldPlan.key
+		if _, ok := members[key]; !ok {
+			for _, candidate := range keys {
+				if strings.EqualFold(candidate, key) {
+					key = candidate
+					break
+				}
+			}
+		}
+		raw, ok := members[key]
+		if !ok {
+			continue
+		}
+		used[key] = true
+		if err := json.Unmarshal(raw, field.Addr().Interface()); err != nil {
+			return fmt.Errorf("Field '%s': %w", fieldPlan.key, err)
+		}
+	}
+	return nil
+}
+
+// }}}
+
+// Relationship fields {{{
+
+// Keys of the Paragraph that hold relationships between packages. Since
+// these are left as strings on some Structs (such as Depends on the
+// BinaryIndex), they're written out as a dependency.Dependency tree if
+// they're found in the Paragraph, so the JSON is the same shape no matter
+// which Struct it came from.
+var relationshipFields = map[string]bool{
+	"depends":               true,
+	"pre-depends":           true,
+	"recommends":            true,
+	"suggests":              true,
+	"enhances":              true,
+	"breaks":                true,
+	"conflicts":             true,
+	"replaces":              true,
+	"provides":              true,
+	"built-using":           true,
+	"build-depends":         true,
+	"build-depends-arch":    true,
+	"build-depends-indep":   true,
+	"build-conflicts":       true,
+	"build-conflicts-arch":  true,
+	"build-conflicts-indep": true,
+}
+
+func paragraphJSONValue(key, value string) (interface{}, error) {
+	if !relationshipFields[strings.ToLower(key)] {
+		return value, nil
+	}
+	return dependency.Parse(value)
+}
+
+func paragraphValueFromJSON(key string, raw json.RawMessage) (string, error) {
+	if relationshipFields[strings.ToLower(key)] && bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		dep := dependency.Dependency{}
+		if err := json.Unmarshal(raw, &dep); err != nil {
+			return "", err
+		}
+		return dep.String(), nil
+	}
+	value := ""
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// }}}
+
+// JSON methods {{{
+
+// The Paragraph types in this package all use MarshalJSON and UnmarshalJSON
+// for their JSON representation.
+
+func (d DSC) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(&d)
+}
+
+func (d *DSC) UnmarshalJSON(data []byte) error {
+	return UnmarshalJSON(d, data)
+}
+
+func (c Changes) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(&c)
+}
+
+func (c *Changes) UnmarshalJSON(data []byte) error {
+	return UnmarshalJSON(c, data)
+}
+
+func (b BinaryIndex) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(&b)
+}
+
+func (b *BinaryIndex) UnmarshalJSON(data []byte) error {
+	return UnmarshalJSON(b, data)
+}
+
+func (s SourceIndex) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(&s)
+}
+
+func (s *SourceIndex) UnmarshalJSON(data []byte) error {
+	return UnmarshalJSON(s, data)
+}
+
+func (s SourceParagraph) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(&s)
+}
+
+func (s *SourceParagraph) UnmarshalJSON(data []byte) error {
+	return UnmarshalJSON(s, data)
+}
+
+func (r Release) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(&r)
+}
+
+func (r *Release) UnmarshalJSON(data []byte) error {
+	return UnmarshalJSON(r, data)
+}
+
+func (b BinaryParagraph) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(&b)
+}
+
+func (b *BinaryParagraph) UnmarshalJSON(data []byte) error {
+	return UnmarshalJSON(b, data)
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,99 @@
+package control_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/dependency"
+)
+
+func TestDSCJSONRoundTrip(t *testing.T) {
+	// Test DSC {{{
+	reader := bufio.NewReader(strings.NewReader(`Format: 3.0 (quilt)
+Source: fbautostart
+Binary: fbautostart
+Architecture: any
+Version: 1:2.718281828-1
+Maintainer: Paul Tagliamonte <paultag@ubuntu.com>
+Build-Depends: debhelper (>= 9), foo [amd64] | bar <!nocheck>
+X-Extra: Something
+Files:
+ 06495f9b23b1c9b1bf35c2346cb48f63 92748 fbautostart_2.718281828.orig.tar.gz
+`))
+	// }}}
+	dsc, err := control.ParseDsc(reader, "")
+	isok(t, err)
+
+	data, err := json.Marshal(dsc)
+	isok(t, err)
+
+	generic := map[string]interface{}{}
+	isok(t, json.Unmarshal(data, &generic))
+	assert(t, generic["Source"] == "fbautostart")
+	assert(t, generic["Version"] == "1:2.718281828-1")
+	assert(t, generic["X-Extra"] == "Something")
+	_, ok := generic["Checksums-Sha256"]
+	assert(t, !ok)
+	arches := generic["Architecture"].([]interface{})
+	assert(t, len(arches) == 1 && arches[0] == "any")
+
+	relations := generic["Build-Depends"].(map[string]interface{})["relations"].([]interface{})
+	assert(t, len(relations) == 2)
+	possibilities := relations[1].(map[string]interface{})["possibilities"].([]interface{})
+	assert(t, len(possibilities) == 2)
+	foo := possibilities[0].(map[string]interface{})
+	assert(t, foo["name"] == "foo")
+	assert(t, foo["architectures"].(map[string]interface{})["architectures"].([]interface{})[0] == "amd64")
+
+	files := generic["Files"].([]interface{})
+	assert(t, files[0].(map[string]interface{})["size"] == float64(92748))
+
+	back := control.DSC{}
+	isok(t, json.Unmarshal(data, &back))
+	assert(t, back.Source == "fbautostart")
+	assert(t, back.Version.Epoch == 1)
+	assert(t, back.Version.Revision == "1")
+	assert(t, back.Architectures[0].CPU == "any")
+	assert(t, back.BuildDepends.String() == dsc.BuildDepends.String())
+	assert(t, back.Files[0].Hash == "06495f9b23b1c9b1bf35c2346cb48f63")
+	assert(t, back.Values["X-Extra"] == "Something")
+
+	again, err := json.Marshal(back)
+	isok(t, err)
+	assert(t, string(again) == string(data))
+}
+
+func TestBinaryIndexJSON(t *testing.T) {
+	index := control.BinaryIndex{}
+	isok(t, json.Unmarshal([]byte(`{
+		"package": "fbautostart",
+		"Version": "2.718281828-1",
+		"Installed-Size": 42,
+		"Depends": {"relations": [{"possibilities": [
+			{"name": "libc6", "version": {"operator": ">=", "number": "2.4"}}
+		]}]}
+	}`), &index))
+	assert(t, index.Package == "fbautostart")
+	assert(t, index.InstalledSize == 42)
+	assert(t, index.GetDepends().String() == "libc6 (>= 2.4)")
+	assert(t, index.Version.Version == "2.718281828")
+
+	data, err := json.Marshal(index)
+	isok(t, err)
+	generic := map[string]json.RawMessage{}
+	isok(t, json.Unmarshal(data, &generic))
+	depends := dependency.Dependency{}
+	isok(t, json.Unmarshal(generic["Depends"], &depends))
+	assert(t, depends.Relations[0].Possibilities[0].Version.Operator == ">=")
+	assert(t, !strings.Contains(string(generic["Depends"]), "architectures"))
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+	index := control.BinaryIndex{}
+	notok(t, json.Unmarshal([]byte(`{"Installed-Size": "many"}`), &index))
+	notok(t, json.Unmarshal([]byte(`{"X-Extra": 1}`), &index))
+	notok(t, json.Unmarshal([]byte(`[]`), &index))
+}
@@ -0,0 +1,184 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"pault.ag/go/debian/dependency"
+)
+
+// Package-List {{{
+
+// A PackageListEntry is a single line of the Package-List field of a .dsc
+// file (or a Sources index), describing one of the binary packages the
+// source package builds:
+//
+//	hello-udeb udeb debian-installer optional arch=linux-any profile=!noudeb
+//
+// The `arch=` option is parsed into Architectures, and the `profile=`
+// option (the Build-Profiles of the package, with each `<...>` group
+// written as a comma separated list, and groups separated by `+`) into
+// Profiles. Any other options (such as `essential=yes`) are kept in
+// Options.
+type PackageListEntry struct {
+	Package  string `json:"package"`
+	Type     string `json:"type"`
+	Section  string `json:"section"`
+	Priority string `json:"priority"`
+
+	Architectures []dependency.Arch     `json:"architectures,omitempty"`
+	Profiles      []dependency.StageSet `json:"profiles,omitempty"`
+	Options       map[string]string     `json:"options,omitempty"`
+}
+
+func (e *PackageListEntry) UnmarshalControl(data string) error {
+	fields := strings.Fields(data)
+	if len(fields) < 4 {
+		return fmt.Errorf("Package-List entry '%s' has too few fields", data)
+	}
+	*e = PackageListEntry{
+		Package:  fields[0],
+		Type:     fields[1],
+		Section:  fields[2],
+		Priority: fields[3],
+		Options:  map[string]string{},
+	}
+
+	for _, option := range fields[4:] {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok {
+			return fmt.Errorf("Package-List entry '%s' has a bad option '%s'", data, option)
+		}
+		switch key {
+		case "arch":
+			for _, name := range strings.Split(value, ",") {
+				arch, err := dependency.ParseArch(name)
+				if err != nil {
+					return err
+				}
+				e.Architectures = append(e.Architectures, *arch)
+			}
+		case "profile":
+			for _, group := range strings.Split(value, "+") {
+				stageSet := dependency.StageSet{}
+				for _, name := range strings.Split(group, ",") {
+					stage := dependency.Stage{Name: strings.TrimPrefix(name, "!")}
+					stage.Not = stage.Name != name
+					if stage.Name == "" {
+						return fmt.Errorf("Package-List entry '%s' has an empty profile", data)
+					}
+					stageSet.Stages = append(stageSet.Stages, stage)
+				}
+				e.Profiles = append(e.Profiles, stageSet)
+			}
+		default:
+			e.Options[key] = value
+		}
+	}
+	return nil
+}
+
+func (e PackageListEntry) MarshalControl() (string, error) {
+	fields := []string{e.Package, e.Type, e.Section, e.Priority}
+
+	if len(e.Architectures) > 0 {
+		arches := []string{}
+		for _, arch := range e.Architectures {
+			arches = append(arches, arch.String())
+		}
+		fields = append(fields, "arch="+strings.Join(arches, ","))
+	}
+
+	if len(e.Profiles) > 0 {
+		groups := []string{}
+		for _, stageSet := range e.Profiles {
+			stages := []string{}
+			for _, stage := range stageSet.Stages {
+				stages = append(stages, stage.String())
+			}
+			groups = append(groups, strings.Join(stages, ","))
+		}
+		fields = append(fields, "profile="+strings.Join(groups, "+"))
+	}
+
+	keys := []string{}
+	for key := range e.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fields = append(fields, key+"="+e.Options[key])
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// Return true if this binary package is built on the given (concrete)
+// architecture. Entries without an `arch=` option are built everywhere.
+func (e PackageListEntry) BuiltOn(arch dependency.Arch) bool {
+	if len(e.Architectures) == 0 {
+		return true
+	}
+	for _, candidate := range e.Architectures {
+		if candidate.Is(&arch) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return true if this binary package is built with the given build
+// profiles active. Entries without a `profile=` option are always built;
+// otherwise, at least one of the groups must be satisfied.
+func (e PackageListEntry) BuiltWith(profiles []string) bool {
+	if len(e.Profiles) == 0 {
+		return true
+	}
+	for _, stageSet := range e.Profiles {
+		if stageSet.Matches(profiles) {
+			return true
+		}
+	}
+	return false
+}
+
+// PackageList is the parsed Package-List field.
+type PackageList []PackageListEntry
+
+// Return the entries of the PackageList that are built on the given
+// (concrete) architecture, with the given build profiles active. Packages
+// that are Architecture: all are only included if `arch` is `all`.
+func (l PackageList) For(arch dependency.Arch, profiles []string) PackageList {
+	ret := PackageList{}
+	for _, entry := range l {
+		if entry.BuiltOn(arch) && entry.BuiltWith(profiles) {
+			ret = append(ret, entry)
+		}
+	}
+	return ret
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,136 @@
+package control_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/dependency"
+)
+
+const packageListDSC = `Format: 3.0 (quilt)
+Source: hello
+Binary: hello, hello-udeb, hello-doc, hello-stage1
+Architecture: any all
+Version: 2.10-3
+Maintainer: Santiago Vila <sanvila@debian.org>
+Package-List:
+ hello deb devel optional arch=any essential=yes
+ hello-doc deb doc optional arch=all profile=!nodoc
+ hello-stage1 deb devel optional arch=linux-any profile=stage1,!nocheck+stage2
+ hello-udeb udeb debian-installer optional arch=amd64,i386 profile=!noudeb
+`
+
+func parsePackageList(t *testing.T) control.PackageList {
+	dsc, err := control.ParseDsc(bufio.NewReader(strings.NewReader(packageListDSC)), "")
+	isok(t, err)
+	return dsc.PackageList
+}
+
+func TestPackageListParse(t *testing.T) {
+	list := parsePackageList(t)
+	assert(t, len(list) == 4)
+
+	assert(t, list[0].Package == "hello")
+	assert(t, list[0].Type == "deb")
+	assert(t, list[0].Section == "devel")
+	assert(t, list[0].Priority == "optional")
+	assert(t, len(list[0].Architectures) == 1)
+	assert(t, list[0].Architectures[0] == dependency.Any)
+	assert(t, list[0].Options["essential"] == "yes")
+
+	assert(t, len(list[2].Profiles) == 2)
+	assert(t, list[2].Profiles[0].String() == "<stage1 !nocheck>")
+	assert(t, list[2].Profiles[1].String() == "<stage2>")
+
+	assert(t, list[3].Type == "udeb")
+	assert(t, len(list[3].Architectures) == 2)
+	assert(t, list[3].Architectures[1].CPU == "i386")
+
+	entry := control.PackageListEntry{}
+	notok(t, entry.UnmarshalControl("hello deb devel"))
+	notok(t, entry.UnmarshalControl("hello deb devel optional arch"))
+	notok(t, entry.UnmarshalControl("hello deb devel optional profile=stage1,"))
+
+	/* Older .dsc files have no options at all */
+	isok(t, entry.UnmarshalControl("hello deb devel optional"))
+	assert(t, len(entry.Architectures) == 0)
+}
+
+func TestPackageListFor(t *testing.T) {
+	list := parsePackageList(t)
+
+	names := func(list control.PackageList) string {
+		ret := []string{}
+		for _, entry := range list {
+			ret = append(ret, entry.Package)
+		}
+		return strings.Join(ret, " ")
+	}
+
+	amd64 := dependency.Arch{ABI: "gnu", OS: "linux", CPU: "amd64"}
+	arm64 := dependency.Arch{ABI: "gnu", OS: "linux", CPU: "arm64"}
+	hurd := dependency.Arch{ABI: "gnu", OS: "hurd", CPU: "i386"}
+
+	assert(t, names(list.For(amd64, nil)) == "hello hello-udeb")
+	assert(t, names(list.For(arm64, nil)) == "hello")
+	assert(t, names(list.For(amd64, []string{"stage1"})) == "hello hello-stage1 hello-udeb")
+	assert(t, names(list.For(amd64, []string{"stage1", "nocheck"})) == "hello hello-udeb")
+	assert(t, names(list.For(amd64, []string{"stage2", "noudeb"})) == "hello hello-stage1")
+	assert(t, names(list.For(hurd, []string{"stage2"})) == "hello")
+	assert(t, names(list.For(dependency.All, nil)) == "hello-doc")
+	assert(t, names(list.For(dependency.All, []string{"nodoc"})) == "")
+}
+
+func TestPackageListMarshal(t *testing.T) {
+	dsc, err := control.ParseDsc(bufio.NewReader(strings.NewReader(packageListDSC)), "")
+	isok(t, err)
+
+	out := bytes.Buffer{}
+	isok(t, control.Marshal(&out, dsc))
+	assert(t, strings.Contains(out.String(), "Package-List:\n hello deb devel optional arch=any essential=yes\n"))
+	assert(t, strings.Contains(out.String(), "\n hello-stage1 deb devel optional arch=linux-any profile=stage1,!nocheck+stage2\n"))
+
+	again, err := control.ParseDsc(bufio.NewReader(&out), "")
+	isok(t, err)
+	assert(t, len(again.PackageList) == 4)
+	assert(t, again.PackageList[3].Profiles[0].Stages[0].Not)
+}
+
+func TestPackageListBuiltOn(t *testing.T) {
+	for _, test := range []struct {
+		archs string
+		arch  string
+		want  bool
+	}{
+		{"any", "amd64", true},
+		{"any", "kfreebsd-amd64", true},
+		{"any", "hurd-i386", true},
+		{"any", "musl-linux-amd64", true},
+		{"any", "all", false},
+		{"all", "all", true},
+		{"all", "amd64", false},
+		{"linux-any", "amd64", true},
+		{"linux-any", "musl-linux-arm64", true},
+		{"linux-any", "kfreebsd-amd64", false},
+		{"linux-any", "hurd-i386", false},
+		{"kfreebsd-any", "kfreebsd-amd64", true},
+		{"kfreebsd-any", "amd64", false},
+		{"any-amd64", "amd64", true},
+		{"any-amd64", "kfreebsd-amd64", true},
+		{"any-amd64", "kfreebsd-i386", false},
+		{"kfreebsd-amd64", "kfreebsd-amd64", true},
+		{"kfreebsd-amd64", "amd64", false},
+	} {
+		entry := control.PackageListEntry{}
+		isok(t, entry.UnmarshalControl("hello deb devel optional arch="+test.archs))
+		arch, err := dependency.ParseArch(test.arch)
+		isok(t, err)
+		if entry.BuiltOn(*arch) != test.want {
+			t.Fatalf("arch=%s BuiltOn %s: got %t", test.archs, test.arch, !test.want)
+		}
+		assert(t, (len(control.PackageList{entry}.For(*arch, nil)) == 1) == test.want)
+	}
+}
@@ -0,0 +1,169 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// ParallelDecoder {{{
+
+// ParallelDecoder is a Decoder for large control streams, such as the
+// Packages or Sources indices. One goroutine splits the stream into
+// Paragraphs, and a pool of workers unpacks those Paragraphs into Structs.
+//
+// The resulting slice is in the same order as the Paragraphs in the stream,
+// and the error returned (if any) is the error that the Decoder would have
+// hit first when reading the stream in order.
+type ParallelDecoder struct {
+	paragraphReader ParagraphReader
+	workers         int
+}
+
+// NewParallelDecoder {{{
+
+// Create a new ParallelDecoder, which will use `workers` goroutines to
+// unpack Paragraphs. If `workers` is less than 1, the value of GOMAXPROCS
+// will be used. The `keyring` argument has the same meaning as it does to
+// NewParagraphReader.
+func NewParallelDecoder(reader io.Reader, keyring *openpgp.EntityList, workers int) (*ParallelDecoder, error) {
+	pr, err := NewParagraphReader(reader, keyring)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return &ParallelDecoder{
+		paragraphReader: *pr,
+		workers:         workers,
+	}, nil
+}
+
+// }}}
+
+// Decode {{{
+
+// A parallelJob is a single Paragraph, on its way to becoming a Struct.
+// `done` is closed once `value` (or `err`) has been set.
+type parallelJob struct {
+	paragraph *Paragraph
+	value     reflect.Value
+	err       error
+	done      chan struct{}
+}
+
+// Decode the remainder of the stream into `into`, which must be a pointer to
+// a slice of Structs. The rules for unpacking each Paragraph are the same as
+// the Unmarshal API.
+func (d *ParallelDecoder) Decode(into interface{}) error {
+	target := reflect.ValueOf(into)
+	if target.Type().Kind() != reflect.Ptr || target.Elem().Type().Kind() != reflect.Slice {
+		return fmt.Errorf("ParallelDecoder can only decode into a pointer to a slice!")
+	}
+	flavor := target.Elem().Type().Elem()
+	if flavor.Kind() != reflect.Struct {
+		return fmt.Errorf("Can't Decode into a slice of %s", flavor)
+	}
+
+	/* Keep a bounded number of Paragraphs in flight, so that a slow
+	 * worker doesn't let the reader run off with the whole stream. */
+	inFlight := d.workers * 4
+
+	jobs := make(chan *parallelJob, inFlight)
+	ordered := make(chan *parallelJob, inFlight)
+	quit := make(chan struct{})
+	defer close(quit)
+
+	for i := 0; i < d.workers; i++ {
+		go func() {
+			for job := range jobs {
+				job.value = reflect.New(flavor)
+				job.err = decodeStruct(*job.paragraph, job.value)
+				close(job.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		for {
+			paragraph, err := d.paragraphReader.Next()
+			if err == io.EOF {
+				return
+			}
+			job := &parallelJob{paragraph: paragraph, done: make(chan struct{})}
+			if err != nil {
+				/* Hand the read error to the collector in order, after
+				 * everything that came before it. */
+				job.err = err
+				close(job.done)
+			}
+
+			select {
+			case ordered <- job:
+			case <-quit:
+				return
+			}
+
+			if err != nil {
+				return
+			}
+
+			select {
+			case jobs <- job:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	slice := target.Elem()
+	for job := range ordered {
+		<-job.done
+		if job.err != nil {
+			return job.err
+		}
+		slice = reflect.Append(slice, job.value.Elem())
+	}
+	target.Elem().Set(slice)
+	return nil
+}
+
+// }}}
+
+// Signer {{{
+
+func (d *ParallelDecoder) Signer() *openpgp.Entity {
+	return d.paragraphReader.Signer()
+}
+
+// }}}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,92 @@
+package control_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+func TestParallelDecode(t *testing.T) {
+	stream := bytes.Buffer{}
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&stream, "Value: %d\nValue-Two: two\nArches: amd64 i386\n\n", i)
+	}
+
+	decoder, err := control.NewParallelDecoder(&stream, nil, 8)
+	isok(t, err)
+
+	items := []TestStruct{}
+	isok(t, decoder.Decode(&items))
+	assert(t, len(items) == 500)
+	for i, item := range items {
+		assert(t, item.Value == fmt.Sprintf("%d", i))
+		assert(t, item.ValueTwo == "two")
+		assert(t, len(item.Arches) == 2)
+	}
+}
+
+func TestParallelDecodeFirstError(t *testing.T) {
+	stream := bytes.Buffer{}
+	for i := 0; i < 100; i++ {
+		switch i {
+		case 40:
+			/* Missing the required Value field */
+			stream.WriteString("Value-Two: first\n\n")
+		case 60:
+			stream.WriteString("Value: 60\nDepends: foo (>= 1.0) (<= 1.0)\n\n")
+		default:
+			fmt.Fprintf(&stream, "Value: %d\n\n", i)
+		}
+	}
+
+	decoder, err := control.NewParallelDecoder(&stream, nil, 4)
+	isok(t, err)
+
+	items := []TestStruct{}
+	err = decoder.Decode(&items)
+	notok(t, err)
+	assert(t, strings.Contains(err.Error(), "Required field"))
+}
+
+func TestParallelDecodeReadError(t *testing.T) {
+	decoder, err := control.NewParallelDecoder(strings.NewReader(`Value: one
+
+Value: two
+Garbage Line
+`), nil, 0)
+	isok(t, err)
+
+	items := []TestStruct{}
+	err = decoder.Decode(&items)
+	notok(t, err)
+	assert(t, strings.Contains(err.Error(), "Bad line"))
+}
+
+func TestParallelDecodeNotSlice(t *testing.T) {
+	decoder, err := control.NewParallelDecoder(strings.NewReader("Value: one\n"), nil, 2)
+	isok(t, err)
+	notok(t, decoder.Decode(&TestStruct{}))
+}
+
+func BenchmarkParallelDecodeBinaryIndex(b *testing.B) {
+	data := benchmarkBinaryIndexData()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decoder, err := control.NewParallelDecoder(bytes.NewReader(data), nil, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		index := []control.BinaryIndex{}
+		if err := decoder.Decode(&index); err != nil {
+			b.Fatal(err)
+		}
+		if len(index) != 1000 {
+			b.Fatalf("got %d paragraphs", len(index))
+		}
+	}
+}
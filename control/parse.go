@@ -43,10 +43,13 @@ type Paragraph struct {
 
 // Paragraph Helpers {{{
 
+// Field names are case-insensitive (Debian Policy 5.1), so this will set the
+// value of an existing key that matches `key` case-insensitively, keeping
+// the original spelling of the key.
 func (p *Paragraph) Set(key, value string) {
-	if _, found := p.Values[key]; found {
+	if existing, found := p.canonicalKey(key); found {
 		/* We've got the key */
-		p.Values[key] = value
+		p.Values[existing] = value
 		return
 	}
 	/* Otherwise, go ahead and set it in the order and dict,
@@ -55,6 +58,51 @@ func (p *Paragraph) Set(key, value string) {
 	p.Values[key] = value
 }
 
+// Return the value of the given key, compared case-insensitively, as
+// field names are (Debian Policy 5.1). The Values map may be used directly
+// if a byte-for-byte match is wanted.
+func (p *Paragraph) Get(key string) (string, bool) {
+	if existing, found := p.canonicalKey(key); found {
+		return p.Values[existing], true
+	}
+	return "", false
+}
+
+// Remove the given key (compared case-insensitively) from the Paragraph,
+// returning true if the key was present.
+func (p *Paragraph) Delete(key string) bool {
+	existing, found := p.canonicalKey(key)
+	if !found {
+		return false
+	}
+	delete(p.Values, existing)
+	for i, el := range p.Order {
+		if el == existing {
+			p.Order = append(p.Order[:i:i], p.Order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Return the key as it is spelled in this Paragraph, which is equal to the
+// given key under case folding.
+func (p *Paragraph) canonicalKey(key string) (string, bool) {
+	if _, found := p.Values[key]; found {
+		return key, true
+	}
+	for _, el := range p.Order {
+		if len(el) == len(key) && strings.EqualFold(el, key) {
+			return el, true
+		}
+	}
+	return "", false
+}
+
+// Write the Paragraph out in the control file format. A value that starts
+// with a newline (such as any field tagged `multiline:"true"`) is written
+// starting on the line after the key, and neither it nor an empty value is
+// written with a space after the colon.
 func (p *Paragraph) WriteTo(out io.Writer) error {
 	for _, key := range p.Order {
 		value := p.Values[key]
@@ -62,8 +110,16 @@ func (p *Paragraph) WriteTo(out io.Writer) error {
 		value = strings.Replace(value, "\n", "\n ", -1)
 		value = strings.Replace(value, "\n \n", "\n .\n", -1)
 
+		/* Empty values, and values that start on the next line, are
+		 * written without the space after the colon, rather than with
+		 * trailing whitespace. */
+		separator := " "
+		if value == "" || strings.HasPrefix(value, "\n") {
+			separator = ""
+		}
+
 		if _, err := out.Write(
-			[]byte(fmt.Sprintf("%s: %s\n", key, value)),
+			[]byte(fmt.Sprintf("%s:%s%s\n", key, separator, value)),
 		); err != nil {
 			return err
 		}
@@ -71,26 +127,22 @@ func (p *Paragraph) WriteTo(out io.Writer) error {
 	return nil
 }
 
+// Return a new Paragraph, with the values of `other` merged on top of this
+// Paragraph. Keys are compared case-insensitively, and the spelling of the
+// key in this Paragraph is kept.
 func (p *Paragraph) Update(other Paragraph) Paragraph {
 	ret := Paragraph{
 		Order:  []string{},
 		Values: map[string]string{},
 	}
 
-	seen := map[string]bool{}
-
 	for _, el := range p.Order {
 		ret.Order = append(ret.Order, el)
 		ret.Values[el] = p.Values[el]
-		seen[el] = true
 	}
 
 	for _, el := range other.Order {
-		if _, ok := seen[el]; !ok {
-			ret.Order = append(ret.Order, el)
-			seen[el] = true
-		}
-		ret.Values[el] = other.Values[el]
+		ret.Set(el, other.Values[el])
 	}
 
 	return ret
@@ -98,6 +150,37 @@ func (p *Paragraph) Update(other Paragraph) Paragraph {
 
 // }}}
 
+// ParseError {{{
+
+// A ParseError is returned by the ParagraphReader when it encounters a line
+// it is unable to parse. Line and Column are both 1-indexed, and relative to
+// the start of the stream (or the start of the OpenPGP Clearsigned document,
+// if the stream is signed).
+type ParseError struct {
+	Filename string
+	Line     int
+	Column   int
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Filename != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+func (p *ParagraphReader) errorf(column int, format string, args ...interface{}) error {
+	return &ParseError{
+		Filename: p.Filename,
+		Line:     p.line,
+		Column:   column,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// }}}
+
 // ParagraphReader {{{
 
 // Wrapper to allow iteration on a set of Paragraphs without consuming them
@@ -107,8 +190,24 @@ func (p *Paragraph) Update(other Paragraph) Paragraph {
 // unread Paragraph can be returned by calling the `.Next` method on this
 // struct.
 type ParagraphReader struct {
+	// If Strict is set, the ParagraphReader will reject input that it
+	// would otherwise quietly accept; duplicate fields, field names
+	// containing characters not allowed by Debian Policy 5.1, and
+	// continuation lines that don't follow a field.
+	Strict bool
+
+	// Filename is used when reporting a ParseError, if set.
+	Filename string
+
 	reader *bufio.Reader
 	signer *openpgp.Entity
+	line   int
+
+	/* Scratch space, reused between Paragraphs */
+	buf    []byte
+	long   []byte
+	fields []paragraphField
+	keys   map[string]string
 }
 
 // {{{ NewParagraphReader
@@ -173,41 +272,64 @@ func (p *ParagraphReader) All() ([]Paragraph, error) {
 // Consume the io.Reader and return the next parsed Paragraph, modulo
 // garbage lines causing us to return an error.
 func (p *ParagraphReader) Next() (*Paragraph, error) {
-	paragraph := Paragraph{
-		Order:  []string{},
-		Values: map[string]string{},
+	paragraph := Paragraph{}
+	if err := p.nextInto(&paragraph); err != nil {
+		return nil, err
 	}
-	var lastKey string
+	return &paragraph, nil
+}
+
+// A paragraphField is a single Key line (and any continuation lines) that
+// has been read into the ParagraphReader's scratch buffer, but not yet
+// turned into a Paragraph value.
+type paragraphField struct {
+	key     string
+	start   int
+	line    int
+	ordered bool
+}
+
+// Read the next Paragraph into `paragraph`, reusing its Order and Values
+// storage, rather than allocating a fresh Paragraph. All values of the
+// Paragraph share a single backing string, so that a Paragraph costs one
+// allocation, and not one per line.
+func (p *ParagraphReader) nextInto(paragraph *Paragraph) error {
+	p.buf = p.buf[:0]
+	p.fields = p.fields[:0]
+	ordered := 0
 
 	for {
-		line, err := p.reader.ReadString('\n')
-		if err == io.EOF && line != "" {
+		line, err := p.readLine()
+		if err == io.EOF && len(line) != 0 {
 			err = nil
-			line = line + "\n"
 			/* We'll clean up the last of the buffer. */
+			p.long = append(append(p.long[:0], line...), '\n')
+			line = p.long
 		}
 		if err == io.EOF {
 			/* Let's return the parsed paragraph if we have it */
-			if len(paragraph.Order) > 0 {
-				return &paragraph, nil
+			if ordered > 0 {
+				p.flush(paragraph)
+				return nil
 			}
 			/* Else, let's go ahead and drop the EOF out raw */
-			return nil, err
+			return err
 		} else if err != nil {
-			return nil, err
+			return err
 		}
 
-		if line == "\n" || line == "\r\n" {
-			if len(paragraph.Order) == 0 {
+		if isBlankLine(line) {
+			if ordered == 0 {
 				/* Skip over any number of blank lines between paragraphs. */
 				continue
 			}
 			/* Lines are ended by a blank line; so we're able to go ahead
 			 * and return this guy as-is. All set. Done. Finished. */
-			return &paragraph, nil
+			p.flush(paragraph)
+			return nil
 		}
 
-		if strings.HasPrefix(line, "#") {
+		if line[0] == '#' {
 			continue // skip comments
 		}
 
@@ -220,7 +342,7 @@ func (p *ParagraphReader) Next() (*Paragraph, error) {
 		 * Key line is a Key/Value mapping.
 		 */
 
-		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+		if line[0] == ' ' || line[0] == '\t' {
 			/* This is a continuation line; so we're going to go ahead and
 			 * clean it up, and throw it into the list. We're going to remove
 			 * the first character (which we now know is whitespace), and if
@@ -228,42 +350,156 @@ func (p *ParagraphReader) Next() (*Paragraph, error) {
 			 * (since " .\n" is actually "\n"). We only trim off space on the
 			 * right hand, because indentation under the whitespace is up to
 			 * the data format. Not us. */
+			line = bytes.TrimRightFunc(line[1:], unicode.IsSpace)
 
-			/* TrimFunc(line[1:], unicode.IsSpace) is identical to calling
-			 * TrimSpace. */
-			line = strings.TrimRightFunc(line[1:], unicode.IsSpace)
+			if len(line) == 1 && line[0] == '.' {
+				line = line[:0]
+			}
 
-			if line == "." {
-				line = ""
+			if len(p.fields) == 0 {
+				if p.Strict {
+					return p.errorf(1, "Continuation line with no field to continue")
+				}
+				/* A continuation with no Key to continue. */
+				p.fields = append(p.fields, paragraphField{start: len(p.buf)})
 			}
 
-			if paragraph.Values[lastKey] == "" {
-				paragraph.Values[lastKey] = line + "\n"
+			/* The value being continued is always the last one in the
+			 * buffer, since it belongs to the last Key we've seen. */
+			if len(p.buf) == p.fields[len(p.fields)-1].start {
+				p.buf = append(p.buf, line...)
+				p.buf = append(p.buf, '\n')
 			} else {
-				if !strings.HasSuffix(paragraph.Values[lastKey], "\n") {
-					paragraph.Values[lastKey] = paragraph.Values[lastKey] + "\n"
+				if p.buf[len(p.buf)-1] != '\n' {
+					p.buf = append(p.buf, '\n')
 				}
-				paragraph.Values[lastKey] = paragraph.Values[lastKey] + line + "\n"
+				p.buf = append(p.buf, line...)
+				p.buf = append(p.buf, '\n')
 			}
 			continue
 		}
 
 		/* So, if we're here, we've got a key line. Let's go ahead and split
 		 * this on the first key, and set that guy */
-		els := strings.SplitN(line, ":", 2)
-		if len(els) != 2 {
-			return nil, fmt.Errorf("Bad line: '%s' has no ':'", line)
+		colon := bytes.IndexByte(line, ':')
+		if colon == -1 {
+			return p.errorf(1, "Bad line: '%s' has no ':'", bytes.TrimRight(line, "\r\n"))
+		}
+
+		if p.Strict {
+			if err := p.checkKey(line[:colon]); err != nil {
+				return err
+			}
 		}
 
 		/* We'll go ahead and take off any leading spaces */
-		lastKey = strings.TrimSpace(els[0])
-		value := strings.TrimSpace(els[1])
+		key := p.intern(bytes.TrimSpace(line[:colon]))
+
+		if p.Strict {
+			for _, field := range p.fields {
+				if field.ordered && strings.EqualFold(field.key, key) {
+					return p.errorf(1, "Duplicate field '%s' (first seen on line %d)", key, field.line)
+				}
+			}
+		}
+
+		p.fields = append(p.fields, paragraphField{
+			key:     key,
+			start:   len(p.buf),
+			line:    p.line,
+			ordered: true,
+		})
+		ordered++
+		p.buf = append(p.buf, bytes.TrimSpace(line[colon+1:])...)
+	}
+}
 
-		paragraph.Order = append(paragraph.Order, lastKey)
-		paragraph.Values[lastKey] = value
+// Turn the fields read into the scratch buffer into the Paragraph.
+func (p *ParagraphReader) flush(paragraph *Paragraph) {
+	if paragraph.Values == nil {
+		paragraph.Values = make(map[string]string, len(p.fields))
+	} else {
+		for key := range paragraph.Values {
+			delete(paragraph.Values, key)
+		}
+	}
+	if paragraph.Order == nil {
+		paragraph.Order = make([]string, 0, len(p.fields))
+	}
+	paragraph.Order = paragraph.Order[:0]
+
+	values := string(p.buf)
+	for i, field := range p.fields {
+		end := len(values)
+		if i+1 < len(p.fields) {
+			end = p.fields[i+1].start
+		}
+		if field.ordered {
+			paragraph.Order = append(paragraph.Order, field.key)
+		}
+		paragraph.Values[field.key] = values[field.start:end]
 	}
 }
 
+// Check that the given field name only contains characters allowed by
+// Debian Policy 5.1; US-ASCII characters, excluding control characters,
+// space and colon, and not starting with '#' or '-'.
+func (p *ParagraphReader) checkKey(key []byte) error {
+	if len(key) == 0 {
+		return p.errorf(1, "Empty field name")
+	}
+	if key[0] == '-' {
+		return p.errorf(1, "Field name '%s' starts with '-'", key)
+	}
+	for i, c := range key {
+		if c < '!' || c > '~' {
+			return p.errorf(i+1, "Invalid character %q in field name '%s'", c, key)
+		}
+	}
+	return nil
+}
+
+// Return the next line from the underlying reader, including the trailing
+// newline. The returned slice is only valid until the next call.
+func (p *ParagraphReader) readLine() ([]byte, error) {
+	p.line++
+	line, err := p.reader.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		return line, err
+	}
+	/* This line is longer than the bufio buffer, let's go ahead and
+	 * stitch it together ourselves. */
+	p.long = append(p.long[:0], line...)
+	for err == bufio.ErrBufferFull {
+		line, err = p.reader.ReadSlice('\n')
+		p.long = append(p.long, line...)
+	}
+	return p.long, err
+}
+
+// Return a string for the given Key, reusing the string we created the
+// last time we saw it. Control streams only ever use a handful of Keys.
+func (p *ParagraphReader) intern(key []byte) string {
+	if it, ok := p.keys[string(key)]; ok {
+		return it
+	}
+	ret := string(key)
+	if p.keys == nil {
+		p.keys = map[string]string{}
+	}
+	if len(p.keys) < maxInternedKeys {
+		p.keys[ret] = ret
+	}
+	return ret
+}
+
+const maxInternedKeys = 512
+
+func isBlankLine(line []byte) bool {
+	return (len(line) == 1 && line[0] == '\n') ||
+		(len(line) == 2 && line[0] == '\r' && line[1] == '\n')
+}
+
 // }}}
 
 // decodeClearsig {{{
@@ -295,6 +531,10 @@ func (p *ParagraphReader) decodeClearsig(keyring *openpgp.EntityList) error {
 		return fmt.Errorf("Invalid clearsigned input")
 	}
 
+	/* Line numbers in errors should line up with the document as
+	 * given to us, so let's skip past the armor headers. */
+	p.line = clearsignHeaderLines(signedData)
+
 	if keyring == nil {
 		/* As a special case, if the keyring is nil, we can go ahead
 		 * and assume this data isn't intended to be checked against the
@@ -321,6 +561,26 @@ func (p *ParagraphReader) decodeClearsig(keyring *openpgp.EntityList) error {
 	return nil
 }
 
+// Return the number of lines before the signed text of a Clearsigned
+// document; the "-----BEGIN PGP SIGNED MESSAGE-----" line, any armor
+// headers, and the blank line after them.
+func clearsignHeaderLines(data []byte) int {
+	lines := 0
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i == -1 {
+			break
+		}
+		line := data[:i]
+		data = data[i+1:]
+		lines++
+		if lines > 1 && len(bytes.TrimSpace(line)) == 0 {
+			return lines
+		}
+	}
+	return 0
+}
+
 // }}}
 
 // }}}
@@ -176,6 +176,40 @@ func TestParagraphSet(t *testing.T) {
 	assert(t, para.Values["british"] == "redcoat")
 }
 
+func TestParagraphCaseInsensitive(t *testing.T) {
+	para := control.Paragraph{
+		Order:  nil,
+		Values: map[string]string{},
+	}
+	para.Set("Build-depends", "foo")
+	value, ok := para.Get("Build-Depends")
+	assert(t, ok)
+	assert(t, value == "foo")
+	_, ok = para.Get("Build-Depends-Indep")
+	assert(t, !ok)
+
+	// Setting a key with a different case keeps the original spelling.
+	para.Set("BUILD-DEPENDS", "bar")
+	assert(t, len(para.Order) == 1)
+	assert(t, para.Order[0] == "Build-depends")
+	assert(t, para.Values["Build-depends"] == "bar")
+
+	updated := para.Update(control.Paragraph{
+		Order:  []string{"build-DEPENDS", "Section"},
+		Values: map[string]string{"build-DEPENDS": "baz", "Section": "devel"},
+	})
+	assert(t, len(updated.Order) == 2)
+	assert(t, updated.Order[0] == "Build-depends")
+	assert(t, updated.Values["Build-depends"] == "baz")
+	assert(t, updated.Values["Section"] == "devel")
+
+	reader, err := control.NewParagraphReader(strings.NewReader("Key: one\nkey: two\n"), nil)
+	isok(t, err)
+	reader.Strict = true
+	_, err = reader.Next()
+	notok(t, err)
+}
+
 func TestWhitespacePrefixedLines(t *testing.T) {
 	// Reader {{{
 	reader, err := control.NewParagraphReader(strings.NewReader(`Key1: one
@@ -313,4 +347,99 @@ func TestLineWrapping(t *testing.T) {
 `)
 }
 
+func TestParseErrorPosition(t *testing.T) {
+	reader, err := control.NewParagraphReader(strings.NewReader(`Key1: one
+
+Key2: two
+Garbage Line
+`), nil)
+	isok(t, err)
+	reader.Filename = "debian/control"
+
+	_, err = reader.Next()
+	isok(t, err)
+
+	_, err = reader.Next()
+	notok(t, err)
+	parseErr, ok := err.(*control.ParseError)
+	assert(t, ok)
+	assert(t, parseErr.Line == 4)
+	assert(t, parseErr.Column == 1)
+	assert(t, err.Error() == "debian/control:4:1: Bad line: 'Garbage Line' has no ':'")
+}
+
+func TestStrictDuplicateField(t *testing.T) {
+	input := `Key1: one
+Key2: two
+Key1: three
+`
+	reader, err := control.NewParagraphReader(strings.NewReader(input), nil)
+	isok(t, err)
+	para, err := reader.Next()
+	isok(t, err)
+	assert(t, para.Values["Key1"] == "three")
+
+	reader, err = control.NewParagraphReader(strings.NewReader(input), nil)
+	isok(t, err)
+	reader.Strict = true
+	_, err = reader.Next()
+	notok(t, err)
+	parseErr := err.(*control.ParseError)
+	assert(t, parseErr.Line == 3)
+	assert(t, strings.Contains(parseErr.Message, "first seen on line 1"))
+}
+
+func TestStrictInvalidKey(t *testing.T) {
+	for _, input := range []string{
+		"Key One: one\n",
+		"-Key: one\n",
+		"Key\x01: one\n",
+	} {
+		reader, err := control.NewParagraphReader(strings.NewReader(input), nil)
+		isok(t, err)
+		_, err = reader.Next()
+		isok(t, err)
+
+		reader, err = control.NewParagraphReader(strings.NewReader(input), nil)
+		isok(t, err)
+		reader.Strict = true
+		_, err = reader.Next()
+		notok(t, err)
+	}
+
+	reader, err := control.NewParagraphReader(strings.NewReader("Key: one\nBad Key: two\n"), nil)
+	isok(t, err)
+	reader.Strict = true
+	_, err = reader.Next()
+	parseErr := err.(*control.ParseError)
+	assert(t, parseErr.Line == 2)
+	assert(t, parseErr.Column == 4)
+}
+
+func TestStrictLeadingContinuation(t *testing.T) {
+	reader, err := control.NewParagraphReader(strings.NewReader(` continued
+Key: one
+`), nil)
+	isok(t, err)
+	reader.Strict = true
+	_, err = reader.Next()
+	notok(t, err)
+	assert(t, err.(*control.ParseError).Line == 1)
+}
+
+func TestSignedParseErrorPosition(t *testing.T) {
+	input := strings.Replace(signedParagraph, "Closes: 805204\n", "Closes 805204\n", 1)
+	reader, err := control.NewParagraphReader(strings.NewReader(input), nil)
+	isok(t, err)
+	_, err = reader.Next()
+	notok(t, err)
+
+	lines := strings.Split(input, "\n")
+	for i, line := range lines {
+		if line == "Closes 805204" {
+			assert(t, err.(*control.ParseError).Line == i+1)
+		}
+	}
+}
+
 // vim: foldmethod=marker
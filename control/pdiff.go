@@ -0,0 +1,191 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PDiffIndex {{{
+
+// The PDiffIndex is the Index file of a pdiff directory, such as
+// `main/binary-amd64/Packages.diff/Index`. It lists the ed-style patches
+// that take an older copy of an index to the current one, so that clients
+// don't have to download the whole index again.
+//
+// SHA256-History has the checksum of each older copy of the index, named
+// after the patch that applies to it; SHA256-Patches has the checksum of
+// each (uncompressed) patch, and SHA256-Download the checksum of each
+// patch as it's downloaded (usually gzip compressed).
+type PDiffIndex struct {
+	Paragraph
+
+	SHA256Current   PDiffCurrent     `control:"SHA256-Current"`
+	SHA256History   []SHA256FileHash `control:"SHA256-History" delim:"\n" strip:"\n\r\t "`
+	SHA256Patches   []SHA256FileHash `control:"SHA256-Patches" delim:"\n" strip:"\n\r\t "`
+	SHA256Download  []SHA256FileHash `control:"SHA256-Download" delim:"\n" strip:"\n\r\t "`
+	PatchPrecedence string           `control:"X-Patch-Precedence,omitempty"`
+}
+
+// PDiffCurrent is the checksum and size of the current index, which has no
+// filename.
+type PDiffCurrent struct{ FileHash }
+
+func (c *PDiffCurrent) UnmarshalControl(data string) error {
+	vals := strings.Fields(data)
+	if len(vals) != 2 {
+		return fmt.Errorf("Error: Unknown Debian Hash line: '%s'", data)
+	}
+	size, err := strconv.ParseInt(vals[1], 10, 64)
+	if err != nil {
+		return err
+	}
+	c.Algorithm = "sha256"
+	c.Hash = vals[0]
+	c.Size = size
+	return nil
+}
+
+func (c PDiffCurrent) MarshalControl() (string, error) {
+	return fmt.Sprintf("%s %d", c.Hash, c.Size), nil
+}
+
+// Given a path on the filesystem, Parse the file off the disk and return
+// a pointer to a brand new PDiffIndex struct, unless error is set to a
+// value other than nil.
+func ParsePDiffIndexFile(path string) (*PDiffIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParsePDiffIndex(bufio.NewReader(f))
+}
+
+// Given a bufio.Reader, consume the Reader, and return a PDiffIndex object
+// for use.
+func ParsePDiffIndex(reader *bufio.Reader) (*PDiffIndex, error) {
+	ret := &PDiffIndex{}
+	return ret, Unmarshal(ret, reader)
+}
+
+// Merged returns true if every patch in the Index takes its own older copy
+// of the index straight to the current one (`X-Patch-Precedence: merged`),
+// rather than to the next copy in the history.
+func (i *PDiffIndex) Merged() bool {
+	return i.PatchPrecedence == "merged"
+}
+
+// }}}
+
+// PDiffPatch {{{
+
+// A PDiffPatch is a single step from one copy of an index to another.
+type PDiffPatch struct {
+	// The name of the patch, as listed in the Index.
+	Name string
+
+	// The checksum of the index this patch applies to.
+	From FileHash
+
+	// The checksum of the index once this patch has been applied.
+	To FileHash
+
+	// The checksum of the uncompressed patch.
+	Patch FileHash
+
+	// The checksum of the patch as it's downloaded, relative to the
+	// pdiff directory.
+	Download FileHash
+}
+
+// Return the patches that take the copy of the index with the given SHA256
+// checksum to the current one, in the order they must be applied. If the
+// checksum is that of the current index, no patches are returned. If the
+// checksum isn't in the history (too old, or not a copy of this index at
+// all), an error is returned, and the whole index must be fetched instead.
+func (i *PDiffIndex) PatchesFrom(hash string) ([]PDiffPatch, error) {
+	if hash == i.SHA256Current.Hash {
+		return []PDiffPatch{}, nil
+	}
+
+	start := -1
+	for n, entry := range i.SHA256History {
+		if entry.Hash == hash {
+			start = n
+		}
+	}
+	if start == -1 {
+		return nil, fmt.Errorf("No patch applies to an index with the checksum %s", hash)
+	}
+
+	patches := map[string]FileHash{}
+	for _, entry := range i.SHA256Patches {
+		patches[entry.Filename] = entry.FileHash
+	}
+	downloads := map[string]FileHash{}
+	for _, entry := range i.SHA256Download {
+		name := entry.Filename
+		for _, ext := range []string{".gz", ".xz", ".bz2", ".zst"} {
+			if strings.HasSuffix(name, ext) {
+				name = strings.TrimSuffix(name, ext)
+				break
+			}
+		}
+		downloads[name] = entry.FileHash
+	}
+
+	history := i.SHA256History[start:]
+	if i.Merged() {
+		history = history[:1]
+	}
+
+	ret := []PDiffPatch{}
+	for n, entry := range history {
+		patch := PDiffPatch{
+			Name: entry.Filename,
+			From: entry.FileHash,
+			To:   i.SHA256Current.FileHash,
+		}
+		if n+1 < len(history) {
+			patch.To = history[n+1].FileHash
+		}
+
+		var ok bool
+		if patch.Patch, ok = patches[entry.Filename]; !ok {
+			return nil, fmt.Errorf("Patch '%s' is missing from SHA256-Patches", entry.Filename)
+		}
+		if patch.Download, ok = downloads[entry.Filename]; !ok {
+			return nil, fmt.Errorf("Patch '%s' is missing from SHA256-Download", entry.Filename)
+		}
+		ret = append(ret, patch)
+	}
+	return ret, nil
+}
+
+// }}}
+
+// vim: foldmethod=marker
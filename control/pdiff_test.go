@@ -0,0 +1,72 @@
+package control_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+const pdiffIndexFile = `SHA256-Current: 4bbd7d1c2c0bcb6e0bb4b4ccdc3a1e4fe7d6c1d0fd04e0bf2e0af0b0d69e7e01 8945001
+SHA256-History:
+ 1111111111111111111111111111111111111111111111111111111111111111  8944001 T-2024-02-10-0207.56-F-2024-02-09-2010.48
+ 2222222222222222222222222222222222222222222222222222222222222222  8944501 T-2024-02-10-1411.06-F-2024-02-09-2010.48
+SHA256-Patches:
+ 3333333333333333333333333333333333333333333333333333333333333333     9114 T-2024-02-10-0207.56-F-2024-02-09-2010.48
+ 4444444444444444444444444444444444444444444444444444444444444444     3020 T-2024-02-10-1411.06-F-2024-02-09-2010.48
+SHA256-Download:
+ 5555555555555555555555555555555555555555555555555555555555555555     2001 T-2024-02-10-0207.56-F-2024-02-09-2010.48.gz
+ 6666666666666666666666666666666666666666666666666666666666666666      901 T-2024-02-10-1411.06-F-2024-02-09-2010.48.gz
+`
+
+func TestPDiffIndexParse(t *testing.T) {
+	index, err := control.ParsePDiffIndex(bufio.NewReader(strings.NewReader(pdiffIndexFile)))
+	isok(t, err)
+
+	assert(t, index.SHA256Current.Size == 8945001)
+	assert(t, strings.HasPrefix(index.SHA256Current.Hash, "4bbd7d1c"))
+	assert(t, len(index.SHA256History) == 2)
+	assert(t, len(index.SHA256Patches) == 2)
+	assert(t, len(index.SHA256Download) == 2)
+	assert(t, index.SHA256History[1].Size == 8944501)
+	assert(t, index.SHA256Download[0].Filename == "T-2024-02-10-0207.56-F-2024-02-09-2010.48.gz")
+	assert(t, !index.Merged())
+}
+
+func TestPDiffIndexPatchesFrom(t *testing.T) {
+	index, err := control.ParsePDiffIndex(bufio.NewReader(strings.NewReader(pdiffIndexFile)))
+	isok(t, err)
+
+	patches, err := index.PatchesFrom(strings.Repeat("1", 64))
+	isok(t, err)
+	assert(t, len(patches) == 2)
+	assert(t, patches[0].To.Hash == strings.Repeat("2", 64))
+	assert(t, patches[0].Patch.Hash == strings.Repeat("3", 64))
+	assert(t, patches[0].Download.Size == 2001)
+	assert(t, patches[1].To.Hash == index.SHA256Current.Hash)
+	assert(t, patches[1].To.Size == 8945001)
+
+	patches, err = index.PatchesFrom(index.SHA256Current.Hash)
+	isok(t, err)
+	assert(t, len(patches) == 0)
+
+	_, err = index.PatchesFrom(strings.Repeat("9", 64))
+	notok(t, err)
+
+	/* Every merged patch goes straight to the current index */
+	index.PatchPrecedence = "merged"
+	patches, err = index.PatchesFrom(strings.Repeat("1", 64))
+	isok(t, err)
+	assert(t, len(patches) == 1)
+	assert(t, patches[0].To.Hash == index.SHA256Current.Hash)
+}
+
+func TestPDiffIndexMissingPatch(t *testing.T) {
+	index, err := control.ParsePDiffIndex(bufio.NewReader(strings.NewReader(pdiffIndexFile)))
+	isok(t, err)
+
+	index.SHA256Download = index.SHA256Download[:1]
+	_, err = index.PatchesFrom(strings.Repeat("1", 64))
+	notok(t, err)
+}
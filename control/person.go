@@ -0,0 +1,199 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Person {{{
+
+// A Person is an RFC 822 style identity, as found in the Maintainer,
+// Uploaders and Changed-By fields, such as `John Doe <jdoe@example.com>`.
+// The Name may be quoted (`"Doe, John" <jdoe@example.com>`), which it must
+// be if it contains a comma.
+type Person struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Parse a single Person. The usual form is `Name <email>`, but a bare
+// email address, a bare name, and the older `email (Name)` form are also
+// understood. An empty string is the zero Person.
+func ParsePerson(data string) (Person, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return Person{}, nil
+	}
+
+	if i := strings.LastIndex(data, "<"); i != -1 {
+		if !strings.HasSuffix(data, ">") {
+			return Person{}, fmt.Errorf("Person '%s' has an unterminated email address", data)
+		}
+		name, err := unquoteName(strings.TrimSpace(data[:i]))
+		if err != nil {
+			return Person{}, fmt.Errorf("Person '%s': %w", data, err)
+		}
+		return Person{
+			Name:  name,
+			Email: strings.TrimSpace(data[i+1 : len(data)-1]),
+		}, nil
+	}
+
+	if i := strings.Index(data, "("); i != -1 && strings.HasSuffix(data, ")") {
+		return Person{
+			Name:  strings.TrimSpace(data[i+1 : len(data)-1]),
+			Email: strings.TrimSpace(data[:i]),
+		}, nil
+	}
+
+	if strings.Contains(data, "@") && !strings.ContainsAny(data, " \t\"") {
+		return Person{Email: data}, nil
+	}
+	name, err := unquoteName(data)
+	if err != nil {
+		return Person{}, fmt.Errorf("Person '%s': %w", data, err)
+	}
+	return Person{Name: name}, nil
+}
+
+// Remove the quotes (and backslash escapes) from a quoted Name.
+func unquoteName(name string) (string, error) {
+	if !strings.HasPrefix(name, `"`) {
+		return name, nil
+	}
+	ret := strings.Builder{}
+	escaped := false
+	for i, r := range name[1:] {
+		switch {
+		case escaped:
+			ret.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			if i+2 != len(name) {
+				return "", fmt.Errorf("Unexpected text after the quoted name")
+			}
+			return ret.String(), nil
+		default:
+			ret.WriteRune(r)
+		}
+	}
+	return "", fmt.Errorf("Unterminated quoted name")
+}
+
+// Return the Person as `Name <email>`, quoting the Name if it has any
+// characters that would otherwise be misread, such as a comma.
+func (p Person) String() string {
+	name := p.Name
+	if strings.ContainsAny(name, `,"<>()\`) {
+		name = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+	}
+	switch {
+	case p.Email == "":
+		return name
+	case name == "":
+		return p.Email
+	}
+	return fmt.Sprintf("%s <%s>", name, p.Email)
+}
+
+func (p *Person) UnmarshalControl(data string) error {
+	var err error
+	*p, err = ParsePerson(data)
+	return err
+}
+
+func (p Person) MarshalControl() (string, error) {
+	return p.String(), nil
+}
+
+// }}}
+
+// People {{{
+
+// People is a comma separated list of Persons, as found in the Uploaders
+// field. Unlike a plain slice with a `delim:","` tag, commas inside a
+// quoted Name (or an email address) don't split a Person in two.
+type People []Person
+
+// Parse a comma separated list of Persons. Empty entries (such as those
+// left by a trailing comma) are skipped.
+func ParsePeople(data string) (People, error) {
+	ret := People{}
+	for _, entry := range splitPeople(data) {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		person, err := ParsePerson(entry)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, person)
+	}
+	return ret, nil
+}
+
+// Split on the commas that aren't inside quotes or angle brackets.
+func splitPeople(data string) []string {
+	ret := []string{}
+	quoted, escaped, angled := false, false, false
+	start := 0
+	for i, r := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case quoted:
+		case r == '<':
+			angled = true
+		case r == '>':
+			angled = false
+		case r == ',' && !angled:
+			ret = append(ret, data[start:i])
+			start = i + 1
+		}
+	}
+	return append(ret, data[start:])
+}
+
+func (p *People) UnmarshalControl(data string) error {
+	var err error
+	*p, err = ParsePeople(data)
+	return err
+}
+
+func (p People) MarshalControl() (string, error) {
+	entries := []string{}
+	for _, person := range p {
+		entries = append(entries, person.String())
+	}
+	return strings.Join(entries, ", "), nil
+}
+
+// }}}
+
+// vim: foldmethod=marker
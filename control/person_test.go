@@ -0,0 +1,119 @@
+package control_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+func TestPersonParse(t *testing.T) {
+	for data, want := range map[string]control.Person{
+		"John Doe <jdoe@example.com>":          {Name: "John Doe", Email: "jdoe@example.com"},
+		`"Doe, John" <jdoe@example.com>`:       {Name: "Doe, John", Email: "jdoe@example.com"},
+		`"John \"JD\" Doe" <jdoe@example.com>`: {Name: `John "JD" Doe`, Email: "jdoe@example.com"},
+		"  John Doe   <jdoe@example.com>  ":    {Name: "John Doe", Email: "jdoe@example.com"},
+		"<jdoe@example.com>":                   {Email: "jdoe@example.com"},
+		"jdoe@example.com":                     {Email: "jdoe@example.com"},
+		"jdoe@example.com (John Doe)":          {Name: "John Doe", Email: "jdoe@example.com"},
+		"John Doe":                             {Name: "John Doe"},
+		"":                                     {},
+	} {
+		person, err := control.ParsePerson(data)
+		isok(t, err)
+		if person != want {
+			t.Fatalf("Parsing %q got %#v, want %#v", data, person, want)
+		}
+	}
+
+	for _, data := range []string{
+		"John Doe <jdoe@example.com",
+		`"John Doe <jdoe@example.com>`,
+		`"John" Doe <jdoe@example.com>`,
+	} {
+		_, err := control.ParsePerson(data)
+		notok(t, err)
+	}
+}
+
+func TestPersonString(t *testing.T) {
+	assert(t, control.Person{Name: "John Doe", Email: "jdoe@example.com"}.String() == "John Doe <jdoe@example.com>")
+	assert(t, control.Person{Name: "Doe, John", Email: "jdoe@example.com"}.String() == `"Doe, John" <jdoe@example.com>`)
+	assert(t, control.Person{Name: `John "JD" Doe`, Email: "jdoe@example.com"}.String() == `"John \"JD\" Doe" <jdoe@example.com>`)
+	assert(t, control.Person{Email: "jdoe@example.com"}.String() == "jdoe@example.com")
+	assert(t, control.Person{}.String() == "")
+
+	for _, person := range []control.Person{
+		{Name: "Doe, John", Email: "jdoe@example.com"},
+		{Name: `John "JD" \ Doe`, Email: "jdoe@example.com"},
+	} {
+		again, err := control.ParsePerson(person.String())
+		isok(t, err)
+		assert(t, again == person)
+	}
+}
+
+func TestPeopleParse(t *testing.T) {
+	people, err := control.ParsePeople(`John Doe <jdoe@example.com>,
+ "Roe, Jane" <jroe@example.com>, Odd <odd,person@example.com>,`)
+	isok(t, err)
+	assert(t, len(people) == 3)
+	assert(t, people[1].Name == "Roe, Jane")
+	assert(t, people[2].Email == "odd,person@example.com")
+
+	people, err = control.ParsePeople("")
+	isok(t, err)
+	assert(t, len(people) == 0)
+}
+
+const personDSC = `Format: 3.0 (quilt)
+Source: hello
+Binary: hello
+Architecture: any
+Version: 2.10-3
+Maintainer: Santiago Vila <sanvila@debian.org>
+Uploaders: "Doe, John" <jdoe@example.com>, Jane Roe <jroe@example.com>
+`
+
+func TestPersonFields(t *testing.T) {
+	dsc, err := control.ParseDsc(bufio.NewReader(strings.NewReader(personDSC)), "")
+	isok(t, err)
+	assert(t, dsc.Maintainer.Email == "sanvila@debian.org")
+	assert(t, len(dsc.Uploaders) == 2)
+	assert(t, dsc.Uploaders[0].Name == "Doe, John")
+
+	maintainers := dsc.Maintainers()
+	assert(t, len(maintainers) == 3)
+	assert(t, maintainers[2].Email == "jroe@example.com")
+
+	out := bytes.Buffer{}
+	isok(t, control.Marshal(&out, dsc))
+	assert(t, strings.Contains(out.String(), "\nUploaders: \"Doe, John\" <jdoe@example.com>, Jane Roe <jroe@example.com>\n"))
+
+	encoded, err := json.Marshal(dsc)
+	isok(t, err)
+	decoded := map[string]interface{}{}
+	isok(t, json.Unmarshal(encoded, &decoded))
+	maintainer := decoded["Maintainer"].(map[string]interface{})
+	assert(t, maintainer["email"] == "sanvila@debian.org")
+	assert(t, len(decoded["Uploaders"].([]interface{})) == 2)
+
+	again := control.DSC{}
+	isok(t, json.Unmarshal(encoded, &again))
+	assert(t, again.Uploaders[0] == dsc.Uploaders[0])
+}
+
+func TestSourceIndexUploaders(t *testing.T) {
+	sources := []control.SourceIndex{}
+	isok(t, control.Unmarshal(&sources, strings.NewReader(`Package: hello
+Maintainer: Santiago Vila <sanvila@debian.org>
+Uploaders: "Doe, John" <jdoe@example.com>,
+ Jane Roe <jroe@example.com>
+`)))
+	assert(t, len(sources) == 1)
+	assert(t, len(sources[0].Uploaders) == 2)
+	assert(t, sources[0].Uploaders[1].Name == "Jane Roe")
+}
@@ -0,0 +1,170 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Struct plans {{{
+
+// A structPlan is the result of walking a Struct type once, and recording
+// everything Unmarshal needs to know about each of its fields. Walking the
+// struct tags on every Paragraph is expensive when decoding an index with
+// tens of thousands of entries, so plans are cached per type.
+type structPlan struct {
+	fields []fieldPlan
+}
+
+// A fieldPlan describes how a single Struct field is populated from a
+// Paragraph.
+type fieldPlan struct {
+	index int
+	name  string
+	key   string
+
+	/* Set if this is the anonymous control.Paragraph member */
+	paragraph bool
+
+	/* Set if this is a Struct that should be walked against the same
+	 * Paragraph, rather than unpacked from a single value. */
+	nested bool
+
+	/* Set if the value of `key` should be unpacked into this field. */
+	value bool
+
+	/* Set if this Struct's fields are flattened into the Paragraph when
+	 * marshaling, rather than marshaled as a single value. */
+	inline bool
+
+	required   bool
+	omitEmpty  bool
+	multiline  bool
+	hasDefault bool
+	def        string
+	delim      string
+	join       string
+	strip      string
+}
+
+var (
+	paragraphType      = reflect.TypeOf(Paragraph{})
+	unmarshallableType = reflect.TypeOf((*Unmarshallable)(nil)).Elem()
+	timeType           = reflect.TypeOf(time.Time{})
+
+	structPlans sync.Map
+)
+
+// Return the (cached) plan for the given Struct type.
+func planFor(t reflect.Type) *structPlan {
+	if plan, ok := structPlans.Load(t); ok {
+		return plan.(*structPlan)
+	}
+	plan, _ := structPlans.LoadOrStore(t, buildPlan(t))
+	return plan.(*structPlan)
+}
+
+func buildPlan(t reflect.Type) *structPlan {
+	plan := structPlan{}
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+
+		if fieldType.PkgPath != "" && !fieldType.Anonymous {
+			/* Unexported, we can't set it anyway */
+			continue
+		}
+
+		field := fieldPlan{
+			index:     i,
+			name:      fieldType.Name,
+			key:       fieldType.Name,
+			required:  fieldType.Tag.Get("required") == "true",
+			multiline: fieldType.Tag.Get("multiline") == "true",
+			delim:     " ",
+			strip:     fieldType.Tag.Get("strip"),
+		}
+		field.def, field.hasDefault = fieldType.Tag.Lookup("default")
+
+		/* The control tag is the key, optionally followed by a comma
+		 * separated list of options, such as `control:"Key,omitempty"` */
+		options := strings.Split(fieldType.Tag.Get("control"), ",")
+		if options[0] == "-" && len(options) == 1 {
+			/* If the key is "-", lets go ahead and skip it */
+			continue
+		}
+		if options[0] != "" {
+			field.key = options[0]
+		}
+		for _, option := range options[1:] {
+			switch option {
+			case "omitempty":
+				field.omitEmpty = true
+			case "inline":
+				field.inline = true
+			}
+		}
+
+		if it := fieldType.Tag.Get("delim"); it != "" {
+			field.delim = it
+		}
+		field.join = field.delim
+		if it := fieldType.Tag.Get("join"); it != "" {
+			field.join = it
+		}
+
+		switch {
+		case fieldType.Type == paragraphType:
+			field.paragraph = fieldType.Anonymous
+		case fieldType.Type.Kind() == reflect.Struct &&
+			fieldType.Type != timeType &&
+			!reflect.PtrTo(fieldType.Type).Implements(unmarshallableType):
+			field.nested = true
+		}
+
+		/* Anonymous Structs are flattened into the Paragraph, the same
+		 * way they're walked when unpacking. */
+		if fieldType.Anonymous && field.nested {
+			field.inline = true
+		}
+		field.inline = field.inline && field.nested
+
+		/* Anonymous and inline members are never unpacked from a single
+		 * value; they are either the Paragraph, or walked as a nested
+		 * Struct. */
+		field.value = !fieldType.Anonymous && !field.inline
+
+		if !field.paragraph && !field.nested && !field.value {
+			continue
+		}
+
+		plan.fields = append(plan.fields, field)
+	}
+
+	return &plan
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,113 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"time"
+
+	"pault.ag/go/debian/dependency"
+)
+
+// Release {{{
+
+// The Release file (or InRelease, when signed inline) sits at the top of
+// each suite of an archive, such as `dists/bookworm/Release`. It describes
+// the suite, and has the checksums of all of the indices in the suite.
+type Release struct {
+	Paragraph
+
+	Origin      string
+	Label       string
+	Suite       string
+	Version     string
+	Codename    string
+	Changelogs  string
+	Date        time.Time
+	ValidUntil  time.Time `control:"Valid-Until"`
+	Description string
+
+	NotAutomatic         bool `control:",omitempty"`
+	ButAutomaticUpgrades bool `control:",omitempty"`
+	AcquireByHash        bool `control:"Acquire-By-Hash,omitempty"`
+
+	Architectures []dependency.Arch
+	Components    []string
+
+	MD5Sum []MD5FileHash    `delim:"\n" strip:"\n\r\t "`
+	SHA1   []SHA1FileHash   `delim:"\n" strip:"\n\r\t "`
+	SHA256 []SHA256FileHash `delim:"\n" strip:"\n\r\t "`
+	SHA512 []SHA512FileHash `delim:"\n" strip:"\n\r\t "`
+}
+
+// Given a path on the filesystem, Parse the file off the disk and return
+// a pointer to a brand new Release struct, unless error is set to a value
+// other than nil.
+func ParseReleaseFile(path string) (*Release, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseRelease(bufio.NewReader(f))
+}
+
+// Given a bufio.Reader, consume the Reader, and return a Release object
+// for use. The OpenPGP signature of an InRelease file is not checked; use
+// a ParagraphReader with a keyring to do that.
+func ParseRelease(reader *bufio.Reader) (*Release, error) {
+	ret := &Release{}
+	return ret, Unmarshal(ret, reader)
+}
+
+// Return the best (strongest) checksum of each index listed in the Release
+// file, keyed by path relative to the Release file. Only SHA256 and SHA512
+// are considered; indices that only have weaker checksums are left out.
+//
+// The ByHash field of each FileHash is set, so it may be used with
+// FileHash.ByHashPath if the Release supports Acquire-By-Hash.
+func (r *Release) Indices() map[string]FileHash {
+	ret := map[string]FileHash{}
+	for _, hash := range r.SHA256 {
+		ret[hash.Filename] = hash.FileHash
+	}
+	for _, hash := range r.SHA512 {
+		ret[hash.Filename] = hash.FileHash
+	}
+	return ret
+}
+
+// Return the path of the given index (relative to the Release file) that
+// should be fetched; the by-hash path if the Release supports
+// Acquire-By-Hash, otherwise the index's own path.
+func (r *Release) IndexPath(hash FileHash) string {
+	if r.AcquireByHash && hash.ByHash != "" {
+		return path.Clean(hash.ByHashPath(hash.Filename))
+	}
+	return hash.Filename
+}
+
+// }}}
+
+// vim: foldmethod=marker
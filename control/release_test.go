@@ -0,0 +1,59 @@
+package control_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"pault.ag/go/debian/control"
+)
+
+const releaseFile = `Origin: Debian
+Label: Debian
+Suite: stable
+Version: 12.5
+Codename: bookworm
+Changelogs: https://metadata.ftp-master.debian.org/changelogs/@CHANGEPATH@_changelog
+Date: Sat, 10 Feb 2024 08:53:05 UTC
+Valid-Until: Sat, 17 Feb 2024 08:53:05 UTC
+Acquire-By-Hash: yes
+No-Support-for-Architecture-all: Packages
+Architectures: all amd64 arm64
+Components: main contrib non-free-firmware
+Description: Debian 12.5 Released 10 February 2024
+MD5Sum:
+ 0ed6d4c8891eb86358b94bb35d9e4da4  1484322 contrib/Contents-all
+ d0a0325a97c42fd5f66a8c3e29bcea64    98581 contrib/Contents-all.gz
+SHA256:
+ 3957f28db16e3f28c7b34ae84f1c929c567de6970f3f1b95dac9b498dd80fe63   738242 main/binary-amd64/Packages.xz
+ 6ba6d4a1bb1c8b50d9ae6a1a4e5bce1c3e4f8d2c8e1e3d6d10e7e2c5a6a1d8f3  8945001 main/binary-amd64/Packages
+`
+
+func TestReleaseParse(t *testing.T) {
+	release, err := control.ParseRelease(bufio.NewReader(strings.NewReader(releaseFile)))
+	isok(t, err)
+
+	assert(t, release.Codename == "bookworm")
+	assert(t, release.Suite == "stable")
+	assert(t, release.AcquireByHash)
+	assert(t, !release.NotAutomatic)
+	assert(t, release.Date.Equal(time.Date(2024, 2, 10, 8, 53, 5, 0, time.UTC)))
+	assert(t, release.ValidUntil.Sub(release.Date) == 7*24*time.Hour)
+	assert(t, len(release.Architectures) == 3)
+	assert(t, release.Architectures[1].CPU == "amd64")
+	assert(t, len(release.Components) == 3)
+	assert(t, len(release.MD5Sum) == 2)
+	assert(t, release.MD5Sum[1].Size == 98581)
+	assert(t, len(release.SHA256) == 2)
+
+	indices := release.Indices()
+	assert(t, len(indices) == 2)
+	xz, ok := indices["main/binary-amd64/Packages.xz"]
+	assert(t, ok)
+	assert(t, xz.Size == 738242)
+	assert(t, release.IndexPath(xz) == "main/binary-amd64/by-hash/SHA256/3957f28db16e3f28c7b34ae84f1c929c567de6970f3f1b95dac9b498dd80fe63")
+
+	release.AcquireByHash = false
+	assert(t, release.IndexPath(xz) == "main/binary-amd64/Packages.xz")
+}
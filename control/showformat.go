@@ -0,0 +1,337 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"pault.ag/go/debian/version"
+)
+
+// ShowFormat {{{
+
+// A ShowFormat is a parsed dpkg-query `--showformat` template, such as
+// `${Package} ${Version;-20} ${binary:Package}\n`, which may be used to
+// format a Paragraph (or any Struct that could be Marshaled) the same way
+// dpkg-query would.
+//
+// Each `${Field}` is replaced with the value of that field (compared
+// case-insensitively), or the empty string if the field isn't set. A width
+// may be given after a semicolon, `${Field;width}`; a positive width pads
+// the value on the left (right-aligning it), and a negative width pads it
+// on the right (left-aligning it). Values longer than the width are not
+// truncated. Multi-line values are folded as they would be in the control
+// file. The escapes `\n`, `\t` and `\\` are understood; any other escaped
+// character is output as-is.
+//
+// These virtual fields understood by dpkg-query are supported:
+//
+//	binary:Package            Package, qualified with the Architecture when
+//	                          needed (see NativeArchitecture)
+//	binary:Synopsis           the first line of the Description
+//	binary:Summary            an alias of binary:Synopsis
+//	source:Package            the source package name
+//	source:Version            the source package version
+//	source:Upstream-Version   the upstream part of the source version
+//	db:Status-Abbrev          the abbreviated Status, such as "ii "
+//	db:Status-Want            the desired action from the Status field
+//	db:Status-Status          the package state from the Status field
+//	db:Status-Eflag           the error flag from the Status field
+//
+// The db-fsys:Files and db-fsys:Last-Modified virtual fields are not
+// supported, since they're read from the dpkg database on disk rather than
+// from the Paragraph; they're formatted as empty strings.
+//
+// Much like dpkg-query, there is no conditional syntax; fields that are
+// missing are simply formatted as empty strings.
+type ShowFormat struct {
+	// The native architecture (such as `amd64`) used to decide if
+	// binary:Package needs to be qualified with an architecture. If empty,
+	// only Multi-Arch: same packages are qualified.
+	NativeArchitecture string
+
+	nodes []showFormatNode
+}
+
+// A showFormatNode is either some literal text, or a field to expand.
+type showFormatNode struct {
+	literal string
+	field   string
+	width   int
+	isField bool
+}
+
+// ParseShowFormat {{{
+
+// Parse a dpkg-query `--showformat` string into a ShowFormat.
+func ParseShowFormat(format string) (*ShowFormat, error) {
+	ret := ShowFormat{}
+	literal := strings.Builder{}
+
+	flush := func() {
+		if literal.Len() > 0 {
+			ret.nodes = append(ret.nodes, showFormatNode{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(format); i++ {
+		switch {
+		case format[i] == '\\' && i+1 < len(format):
+			i++
+			switch format[i] {
+			case 'n':
+				literal.WriteByte('\n')
+			case 't':
+				literal.WriteByte('\t')
+			default:
+				literal.WriteByte(format[i])
+			}
+		case format[i] == '$' && i+1 < len(format) && format[i+1] == '{':
+			end := strings.IndexByte(format[i:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("Missing closing brace for the field at offset %d", i)
+			}
+			node, err := parseShowFormatField(format[i+2 : i+end])
+			if err != nil {
+				return nil, err
+			}
+			flush()
+			ret.nodes = append(ret.nodes, node)
+			i += end
+		default:
+			literal.WriteByte(format[i])
+		}
+	}
+	flush()
+	return &ret, nil
+}
+
+// Parse the inside of a `${Field;width}` expansion.
+func parseShowFormatField(spec string) (showFormatNode, error) {
+	node := showFormatNode{isField: true, field: spec}
+	if semi := strings.IndexByte(spec, ';'); semi != -1 {
+		width, err := strconv.Atoi(spec[semi+1:])
+		if err != nil {
+			return node, fmt.Errorf("Bad field width in '${%s}'", spec)
+		}
+		node.field = spec[:semi]
+		node.width = width
+	}
+	if node.field == "" {
+		return node, fmt.Errorf("Empty field name in '${%s}'", spec)
+	}
+	return node, nil
+}
+
+// }}}
+
+// Format {{{
+
+// Format the given Paragraph.
+func (f *ShowFormat) Format(para Paragraph) string {
+	out := strings.Builder{}
+	for _, node := range f.nodes {
+		if !node.isField {
+			out.WriteString(node.literal)
+			continue
+		}
+
+		value := f.fieldValue(para, node.field)
+		switch {
+		case node.width > 0:
+			out.WriteString(fmt.Sprintf("%*s", node.width, value))
+		case node.width < 0:
+			out.WriteString(fmt.Sprintf("%-*s", -node.width, value))
+		default:
+			out.WriteString(value)
+		}
+	}
+	return out.String()
+}
+
+// Format a Struct (or a pointer to one), by first converting it to a
+// Paragraph the same way Marshal would.
+func (f *ShowFormat) FormatStruct(data interface{}) (string, error) {
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	para, err := convertToParagraph(value)
+	if err != nil {
+		return "", err
+	}
+	return f.Format(*para), nil
+}
+
+// }}}
+
+// Fields {{{
+
+// Return the value of the given (possibly virtual) field.
+func (f *ShowFormat) fieldValue(para Paragraph, field string) string {
+	get := func(key string) string {
+		value, _ := para.Get(key)
+		return value
+	}
+
+	switch strings.ToLower(field) {
+	case "binary:package":
+		return f.binaryPackage(get("Package"), get("Architecture"), get("Multi-Arch"))
+	case "binary:synopsis", "binary:summary":
+		synopsis, _, _ := strings.Cut(get("Description"), "\n")
+		return synopsis
+	case "source:package":
+		if source, _ := splitSource(get("Source")); source != "" {
+			return source
+		}
+		return get("Package")
+	case "source:version":
+		return sourceVersion(para)
+	case "source:upstream-version":
+		ver, err := version.Parse(sourceVersion(para))
+		if err != nil {
+			return ""
+		}
+		return ver.Version
+	case "db:status-abbrev":
+		want, eflag, status := splitStatus(get("Status"))
+		if want == "" {
+			return ""
+		}
+		return statusAbbrev(wantAbbrevs, want) +
+			statusAbbrev(statusAbbrevs, status) +
+			statusAbbrev(eflagAbbrevs, eflag)
+	case "db:status-want":
+		want, _, _ := splitStatus(get("Status"))
+		return want
+	case "db:status-eflag":
+		_, eflag, _ := splitStatus(get("Status"))
+		return eflag
+	case "db:status-status":
+		_, _, status := splitStatus(get("Status"))
+		return status
+	}
+
+	return foldValue(get(field))
+}
+
+// Qualify the package name with its architecture the same way dpkg does
+// when asked for a non-ambiguous name: if it's Multi-Arch: same, or if it
+// is for a foreign architecture.
+func (f *ShowFormat) binaryPackage(name, arch, multiArch string) string {
+	if name == "" || arch == "" {
+		return name
+	}
+	foreign := f.NativeArchitecture != "" && arch != f.NativeArchitecture && arch != "all"
+	if strings.EqualFold(multiArch, "same") || foreign {
+		return name + ":" + arch
+	}
+	return name
+}
+
+// Split a Source field, such as `foo (1.0-1)`, into the name and version.
+func splitSource(source string) (string, string) {
+	name, rest, found := strings.Cut(strings.TrimSpace(source), " ")
+	if !found {
+		return name, ""
+	}
+	rest = strings.TrimSpace(rest)
+	rest = strings.TrimPrefix(rest, "(")
+	rest = strings.TrimSuffix(rest, ")")
+	return name, strings.TrimSpace(rest)
+}
+
+// Return the version of the source package; the version in the Source
+// field if there is one, otherwise the Version of the package itself.
+func sourceVersion(para Paragraph) string {
+	source, _ := para.Get("Source")
+	if _, ver := splitSource(source); ver != "" {
+		return ver
+	}
+	ver, _ := para.Get("Version")
+	return ver
+}
+
+// Split a Status field, such as `install ok installed`, into its parts.
+func splitStatus(status string) (want, eflag, state string) {
+	parts := strings.Fields(status)
+	if len(parts) != 3 {
+		return "", "", ""
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+var (
+	wantAbbrevs = map[string]string{
+		"unknown":   "u",
+		"install":   "i",
+		"hold":      "h",
+		"deinstall": "r",
+		"purge":     "p",
+	}
+	eflagAbbrevs = map[string]string{
+		"ok":        " ",
+		"reinstreq": "R",
+	}
+	statusAbbrevs = map[string]string{
+		"not-installed":    "n",
+		"config-files":     "c",
+		"half-installed":   "H",
+		"unpacked":         "U",
+		"half-configured":  "F",
+		"triggers-awaited": "W",
+		"triggers-pending": "t",
+		"installed":        "i",
+	}
+)
+
+func statusAbbrev(abbrevs map[string]string, value string) string {
+	if abbrev, ok := abbrevs[value]; ok {
+		return abbrev
+	}
+	return "?"
+}
+
+// Fold a multi-line value the same way Paragraph.WriteTo does.
+func foldValue(value string) string {
+	value = strings.TrimSuffix(value, "\n")
+	if !strings.Contains(value, "\n") {
+		return value
+	}
+	lines := strings.Split(value, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "" {
+			lines[i] = "."
+		}
+		lines[i] = " " + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// }}}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,88 @@
+package control_test
+
+import (
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+const showFormatParagraph = `Package: libfoo1
+Status: install ok installed
+Architecture: arm64
+Multi-Arch: same
+Source: foo (1:2.0-3)
+Version: 1:2.0-3+b1
+Description: Foo library
+ This is the long description.
+ .
+ With a second paragraph.
+`
+
+func parseShowFormatParagraph(t *testing.T) control.Paragraph {
+	reader, err := control.NewParagraphReader(strings.NewReader(showFormatParagraph), nil)
+	isok(t, err)
+	para, err := reader.Next()
+	isok(t, err)
+	return *para
+}
+
+func TestShowFormat(t *testing.T) {
+	para := parseShowFormatParagraph(t)
+
+	format, err := control.ParseShowFormat(`${Package} ${version;-12}|${Missing}|${Architecture;8}\n`)
+	isok(t, err)
+	assert(t, format.Format(para) == "libfoo1 1:2.0-3+b1  ||   arm64\n")
+
+	format, err = control.ParseShowFormat(`${db:Status-Abbrev}${binary:Package}\t${binary:Synopsis}`)
+	isok(t, err)
+	assert(t, format.Format(para) == "ii libfoo1:arm64\tFoo library")
+
+	format, err = control.ParseShowFormat(`${source:Package} ${source:Version} ${source:Upstream-Version} ${db:Status-Want}`)
+	isok(t, err)
+	assert(t, format.Format(para) == "foo 1:2.0-3 2.0 install")
+
+	/* These need the dpkg database, and aren't supported */
+	format, err = control.ParseShowFormat(`${db-fsys:Files}|${db-fsys:Last-Modified}`)
+	isok(t, err)
+	assert(t, format.Format(para) == "|")
+
+	format, err = control.ParseShowFormat(`${Description}`)
+	isok(t, err)
+	assert(t, format.Format(para) == strings.TrimSuffix(
+		strings.TrimPrefix(showFormatParagraph[strings.Index(showFormatParagraph, "Description: "):], "Description: "),
+		"\n",
+	))
+}
+
+func TestShowFormatNativeArchitecture(t *testing.T) {
+	para := parseShowFormatParagraph(t)
+	para.Set("Multi-Arch", "foreign")
+
+	format, err := control.ParseShowFormat(`${binary:Package}`)
+	isok(t, err)
+	assert(t, format.Format(para) == "libfoo1")
+
+	format.NativeArchitecture = "amd64"
+	assert(t, format.Format(para) == "libfoo1:arm64")
+
+	format.NativeArchitecture = "arm64"
+	assert(t, format.Format(para) == "libfoo1")
+}
+
+func TestShowFormatStruct(t *testing.T) {
+	format, err := control.ParseShowFormat(`${Package};${Installed-Size;4}`)
+	isok(t, err)
+	out, err := format.FormatStruct(control.BinaryIndex{Package: "foo", InstalledSize: 12})
+	isok(t, err)
+	assert(t, out == "foo;  12")
+}
+
+func TestShowFormatErrors(t *testing.T) {
+	_, err := control.ParseShowFormat(`${Package`)
+	notok(t, err)
+	_, err = control.ParseShowFormat(`${Package;wide}`)
+	notok(t, err)
+	_, err = control.ParseShowFormat(`${}`)
+	notok(t, err)
+}
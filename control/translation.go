@@ -0,0 +1,205 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"bufio"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Description-md5 {{{
+
+// Compute the Description-md5 of the given Description, the same way APT
+// and dak do. The checksum is taken over the Description as it's written
+// in the control file (continuation lines keep their leading space, and
+// empty lines are written as ` .`), with a trailing newline.
+//
+// The Description must be as the ParagraphReader returns it; the synopsis
+// on the first line, followed by the long description, if any.
+func DescriptionMD5(description string) string {
+	lines := strings.Split(strings.TrimRight(description, "\n"), "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] == "" {
+			lines[i] = "."
+		}
+		lines[i] = " " + lines[i]
+	}
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(lines, "\n")+"\n")))
+}
+
+// Return the Description-md5 of this package; the Description-md5 field if
+// it's set, otherwise the checksum of the Description.
+func (index *BinaryIndex) GetDescriptionMD5() string {
+	if index.DescriptionMD5 != "" {
+		return index.DescriptionMD5
+	}
+	return DescriptionMD5(index.Description)
+}
+
+// }}}
+
+// Translation {{{
+
+// A Translation is a single entry of a Translation index, such as
+// `main/i18n/Translation-de`, which has the Description of a package in a
+// single language. Translations are matched up with packages by the
+// Description-md5 of the English Description they translate.
+//
+// The Description is kept in a `Description-<lang>` field, such as
+// `Description-de` or `Description-pt_BR`; its value and language are
+// unpacked into the Description and Language members when parsed with
+// ParseTranslation, and written back out by WriteTranslation.
+type Translation struct {
+	Paragraph
+
+	Package        string `required:"true"`
+	DescriptionMD5 string `control:"Description-md5" required:"true"`
+
+	Language    string `control:"-"`
+	Description string `control:"-"`
+}
+
+// Find the `Description-<lang>` field of the Paragraph.
+func (t *Translation) descriptionKey() (string, bool) {
+	for _, key := range t.Order {
+		if len(key) > len("Description-") &&
+			strings.EqualFold(key[:len("Description-")], "Description-") &&
+			!strings.EqualFold(key, "Description-md5") {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// Given a path on the filesystem, Parse the Translation index off the disk.
+func ParseTranslationFile(path string) ([]Translation, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseTranslation(bufio.NewReader(f))
+}
+
+// Given a reader, parse out a list of Translation structs.
+func ParseTranslation(reader *bufio.Reader) ([]Translation, error) {
+	ret := []Translation{}
+	if err := Unmarshal(&ret, reader); err != nil {
+		return nil, err
+	}
+	for i := range ret {
+		key, ok := ret[i].descriptionKey()
+		if !ok {
+			return nil, fmt.Errorf("Translation of '%s' has no Description field", ret[i].Package)
+		}
+		ret[i].Language = key[len("Description-"):]
+		ret[i].Description = ret[i].Values[key]
+	}
+	return ret, nil
+}
+
+// Write the given Translations out as a Translation index.
+func WriteTranslation(writer io.Writer, translations []Translation) error {
+	out := make([]Translation, len(translations))
+	for i, translation := range translations {
+		if translation.Language == "" {
+			return fmt.Errorf("Translation of '%s' has no Language", translation.Package)
+		}
+		translation.Paragraph = translation.Paragraph.Update(Paragraph{})
+		if key, ok := translation.descriptionKey(); ok {
+			translation.Delete(key)
+		}
+		/* Keep the Description after the Package and Description-md5,
+		 * even for a Translation that wasn't read in from a file */
+		translation.Set("Package", translation.Package)
+		translation.Set("Description-md5", translation.DescriptionMD5)
+		translation.Set("Description-"+translation.Language,
+			strings.TrimRight(translation.Description, "\n"))
+		out[i] = translation
+	}
+	return Marshal(writer, out)
+}
+
+// }}}
+
+// Translations {{{
+
+// Translations holds the Translation indices of one or more languages, for
+// looking up the translated Description of a package.
+type Translations struct {
+	languages map[string]map[string]*Translation
+}
+
+// Create a new, empty, set of Translations.
+func NewTranslations() *Translations {
+	return &Translations{languages: map[string]map[string]*Translation{}}
+}
+
+// Add the entries of a Translation index. Each entry is kept under its own
+// Language, so indices of different languages may be added to the same
+// Translations.
+func (t *Translations) Add(translations []Translation) {
+	for i := range translations {
+		translation := &translations[i]
+		byMD5, ok := t.languages[translation.Language]
+		if !ok {
+			byMD5 = map[string]*Translation{}
+			t.languages[translation.Language] = byMD5
+		}
+		byMD5[translation.DescriptionMD5] = translation
+	}
+}
+
+// Return the Translation of the given package's Description, trying each
+// of the given languages in order. The Translation is matched by the
+// package's Description-md5 (computed from its Description, if the index
+// doesn't have one).
+func (t *Translations) Lookup(index *BinaryIndex, languages ...string) (*Translation, bool) {
+	md5sum := index.GetDescriptionMD5()
+	for _, language := range languages {
+		if translation, ok := t.languages[language][md5sum]; ok {
+			return translation, true
+		}
+	}
+	return nil, false
+}
+
+// Return the Description of the given package in the first of the given
+// languages that it has been translated to. If it hasn't been translated to
+// any of them, the package's own Description is returned.
+//
+// Archives with Translation-en indices often only have the synopsis in the
+// Packages index; pass "en" last to get the full English Description.
+func (t *Translations) Description(index *BinaryIndex, languages ...string) string {
+	if translation, ok := t.Lookup(index, languages...); ok {
+		return translation.Description
+	}
+	return index.Description
+}
+
+// }}}
+
+// vim: foldmethod=marker
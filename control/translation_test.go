@@ -0,0 +1,124 @@
+package control_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+const bashDescription = `GNU Bourne Again SHell
+Bash is an sh-compatible command language interpreter that executes
+commands read from the standard input or from a file.  Bash also
+incorporates useful features from the Korn and C shells (ksh and csh).
+
+Bash is ultimately intended to be a conformant implementation of the
+IEEE POSIX Shell and Tools specification (IEEE Working Group 1003.2).
+
+The Programmable Completion Code, by Ian Macdonald, is now found in
+the bash-completion package.
+`
+
+func TestDescriptionMD5(t *testing.T) {
+	/* As published in the bookworm Packages index */
+	assert(t, control.DescriptionMD5(bashDescription) == "3522aa7b4374048d6450e348a5bb45d9")
+	assert(t, control.DescriptionMD5(strings.TrimSuffix(bashDescription, "\n")) == "3522aa7b4374048d6450e348a5bb45d9")
+
+	index := control.BinaryIndex{Description: bashDescription}
+	assert(t, index.GetDescriptionMD5() == "3522aa7b4374048d6450e348a5bb45d9")
+	index.DescriptionMD5 = "00000000000000000000000000000000"
+	assert(t, index.GetDescriptionMD5() == "00000000000000000000000000000000")
+}
+
+const translationFile = `Package: bash
+Description-md5: 3522aa7b4374048d6450e348a5bb45d9
+Description-de: GNU Bourne Again SHell
+ Bash ist ein sh-kompatibler Befehlssprachen-Interpreter.
+ .
+ Die Programmable Completion ist jetzt im Paket bash-completion.
+
+Package: hello
+Description-md5: 6e9e0eb3c0a1e6c5d4ab9ca5fb0f1e8c
+Description-de: Beispielpaket auf Basis von GNU hello
+`
+
+func TestTranslationParse(t *testing.T) {
+	translations, err := control.ParseTranslation(bufio.NewReader(strings.NewReader(translationFile)))
+	isok(t, err)
+	assert(t, len(translations) == 2)
+	assert(t, translations[0].Package == "bash")
+	assert(t, translations[0].Language == "de")
+	assert(t, strings.HasPrefix(translations[0].Description, "GNU Bourne Again SHell\nBash ist"))
+	assert(t, translations[1].DescriptionMD5 == "6e9e0eb3c0a1e6c5d4ab9ca5fb0f1e8c")
+
+	_, err = control.ParseTranslation(bufio.NewReader(strings.NewReader("Package: bash\nDescription-md5: 3522aa7b4374048d6450e348a5bb45d9\n")))
+	notok(t, err)
+}
+
+func TestTranslationWrite(t *testing.T) {
+	translations, err := control.ParseTranslation(bufio.NewReader(strings.NewReader(translationFile)))
+	isok(t, err)
+
+	out := bytes.Buffer{}
+	isok(t, control.WriteTranslation(&out, translations))
+	assert(t, out.String() == translationFile)
+
+	/* A brand new Translation, and a changed language */
+	translations[1].Language = "pt_BR"
+	translations[1].Description = "pacote de exemplo"
+	translations = append(translations, control.Translation{
+		Package:        "vim",
+		DescriptionMD5: "59e8b8f7757db8b53566d5d119872de8",
+		Language:       "de",
+		Description:    "Vi IMproved - erweiterter vi-Editor\nVim ist fast vollständig kompatibel zu Vi.\n",
+	})
+	out.Reset()
+	isok(t, control.WriteTranslation(&out, translations))
+	assert(t, strings.Contains(out.String(), "Package: hello\nDescription-md5: 6e9e0eb3c0a1e6c5d4ab9ca5fb0f1e8c\nDescription-pt_BR: pacote de exemplo\n"))
+	assert(t, !strings.Contains(out.String(), "Beispielpaket"))
+	assert(t, strings.HasSuffix(out.String(), "Package: vim\nDescription-md5: 59e8b8f7757db8b53566d5d119872de8\nDescription-de: Vi IMproved - erweiterter vi-Editor\n Vim ist fast vollständig kompatibel zu Vi.\n"))
+
+	again, err := control.ParseTranslation(bufio.NewReader(&out))
+	isok(t, err)
+	assert(t, len(again) == 3)
+	assert(t, again[1].Language == "pt_BR")
+
+	translations[2].Language = ""
+	notok(t, control.WriteTranslation(&out, translations))
+}
+
+func TestTranslationsLookup(t *testing.T) {
+	german, err := control.ParseTranslation(bufio.NewReader(strings.NewReader(translationFile)))
+	isok(t, err)
+	english := []control.Translation{{
+		Package:        "bash",
+		DescriptionMD5: "3522aa7b4374048d6450e348a5bb45d9",
+		Language:       "en",
+		Description:    bashDescription,
+	}}
+
+	translations := control.NewTranslations()
+	translations.Add(german)
+	translations.Add(english)
+
+	/* As it would be in a Packages index; only the synopsis */
+	bash := control.BinaryIndex{
+		Package:        "bash",
+		Description:    "GNU Bourne Again SHell",
+		DescriptionMD5: "3522aa7b4374048d6450e348a5bb45d9",
+	}
+	translation, ok := translations.Lookup(&bash, "fr", "de", "en")
+	assert(t, ok)
+	assert(t, translation.Language == "de")
+	assert(t, translations.Description(&bash, "en") == bashDescription)
+	assert(t, translations.Description(&bash, "fr") == "GNU Bourne Again SHell")
+
+	/* With no Description-md5, it's computed from the Description */
+	bash = control.BinaryIndex{Package: "bash", Description: bashDescription}
+	assert(t, strings.Contains(translations.Description(&bash, "de"), "Bash ist"))
+
+	_, ok = translations.Lookup(&control.BinaryIndex{Package: "zsh", Description: "shell"}, "de")
+	assert(t, !ok)
+}
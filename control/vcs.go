@@ -0,0 +1,177 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Vcs {{{
+
+// Vcs is the parsed value of a Vcs-* field, such as
+//
+//	Vcs-Git: https://salsa.debian.org/debian/hello.git -b debian/latest [po]
+//
+// The URL is always the first word. Vcs-Git may be followed by a branch
+// (`-b branch`) and a subdirectory in square brackets; any other words
+// (such as the module of a Vcs-Cvs field) are kept in Module.
+type Vcs struct {
+	URL    string `json:"url"`
+	Branch string `json:"branch,omitempty"`
+	Subdir string `json:"subdir,omitempty"`
+	Module string `json:"module,omitempty"`
+}
+
+// Parse the value of a Vcs-* field.
+func ParseVcs(data string) (Vcs, error) {
+	fields := strings.Fields(data)
+	if len(fields) == 0 {
+		return Vcs{}, nil
+	}
+
+	ret := Vcs{URL: fields[0]}
+	module := []string{}
+	for i := 1; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case field == "-b":
+			if i+1 == len(fields) {
+				return Vcs{}, fmt.Errorf("Vcs field '%s' has no branch after -b", data)
+			}
+			i++
+			ret.Branch = fields[i]
+		case strings.HasPrefix(field, "["):
+			if !strings.HasSuffix(field, "]") || len(field) < 3 {
+				return Vcs{}, fmt.Errorf("Vcs field '%s' has a bad subdirectory", data)
+			}
+			ret.Subdir = field[1 : len(field)-1]
+		default:
+			module = append(module, field)
+		}
+	}
+	ret.Module = strings.Join(module, " ")
+	return ret, nil
+}
+
+// Return the Vcs in the form used by the Vcs-* fields.
+func (v Vcs) String() string {
+	fields := []string{v.URL}
+	if v.Module != "" {
+		fields = append(fields, v.Module)
+	}
+	if v.Branch != "" {
+		fields = append(fields, "-b", v.Branch)
+	}
+	if v.Subdir != "" {
+		fields = append(fields, "["+v.Subdir+"]")
+	}
+	return strings.Join(fields, " ")
+}
+
+func (v *Vcs) UnmarshalControl(data string) error {
+	var err error
+	*v, err = ParseVcs(data)
+	return err
+}
+
+func (v Vcs) MarshalControl() (string, error) {
+	if v.URL == "" {
+		return "", nil
+	}
+	return v.String(), nil
+}
+
+// VcsFields can be included in a struct to read all of the Vcs-* fields
+// of a source package, as BestChecksums is.
+type VcsFields struct {
+	VcsBrowser string `control:"Vcs-Browser"`
+	VcsArch    Vcs    `control:"Vcs-Arch"`
+	VcsBzr     Vcs    `control:"Vcs-Bzr"`
+	VcsCvs     Vcs    `control:"Vcs-Cvs"`
+	VcsDarcs   Vcs    `control:"Vcs-Darcs"`
+	VcsGit     Vcs    `control:"Vcs-Git"`
+	VcsHg      Vcs    `control:"Vcs-Hg"`
+	VcsMtn     Vcs    `control:"Vcs-Mtn"`
+	VcsSvn     Vcs    `control:"Vcs-Svn"`
+}
+
+// Return the version control system the package is maintained in (such as
+// "git"), and the repository, from the first Vcs-* field that is set. If
+// no Vcs-* fields are set, the bool will be false.
+func (v *VcsFields) Vcs() (string, Vcs, bool) {
+	for _, candidate := range []struct {
+		name string
+		vcs  Vcs
+	}{
+		{"git", v.VcsGit},
+		{"hg", v.VcsHg},
+		{"bzr", v.VcsBzr},
+		{"svn", v.VcsSvn},
+		{"darcs", v.VcsDarcs},
+		{"cvs", v.VcsCvs},
+		{"mtn", v.VcsMtn},
+		{"arch", v.VcsArch},
+	} {
+		if candidate.vcs.URL != "" {
+			return candidate.name, candidate.vcs, true
+		}
+	}
+	return "", Vcs{}, false
+}
+
+// }}}
+
+// Dgit {{{
+
+// Dgit is the parsed value of the Dgit field of a .dsc file, which names
+// the git commit the source package was uploaded from. Newer versions of
+// dgit follow the commit with the suite, the git ref and the URL of the
+// dgit repository.
+type Dgit struct {
+	Commit string `json:"commit"`
+	Suite  string `json:"suite,omitempty"`
+	Ref    string `json:"ref,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+func (d *Dgit) UnmarshalControl(data string) error {
+	fields := strings.Fields(data)
+	if len(fields) != 1 && len(fields) != 4 {
+		return fmt.Errorf("Dgit field '%s' should have 1 or 4 words", data)
+	}
+	*d = Dgit{Commit: fields[0]}
+	if len(fields) == 4 {
+		d.Suite, d.Ref, d.URL = fields[1], fields[2], fields[3]
+	}
+	return nil
+}
+
+func (d Dgit) MarshalControl() (string, error) {
+	if d.Suite == "" {
+		return d.Commit, nil
+	}
+	return strings.Join([]string{d.Commit, d.Suite, d.Ref, d.URL}, " "), nil
+}
+
+// }}}
+
+// vim: foldmethod=marker
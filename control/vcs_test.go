@@ -0,0 +1,84 @@
+package control_test
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+func TestVcsParse(t *testing.T) {
+	for data, want := range map[string]control.Vcs{
+		"https://salsa.debian.org/debian/hello.git":                       {URL: "https://salsa.debian.org/debian/hello.git"},
+		"https://salsa.debian.org/debian/hello.git -b debian/latest":      {URL: "https://salsa.debian.org/debian/hello.git", Branch: "debian/latest"},
+		"https://salsa.debian.org/debian/hello.git [po]":                  {URL: "https://salsa.debian.org/debian/hello.git", Subdir: "po"},
+		"https://salsa.debian.org/debian/hello.git -b debian/latest [po]": {URL: "https://salsa.debian.org/debian/hello.git", Branch: "debian/latest", Subdir: "po"},
+		":pserver:anonymous@cvs.example.org:/cvs hello":                   {URL: ":pserver:anonymous@cvs.example.org:/cvs", Module: "hello"},
+	} {
+		vcs, err := control.ParseVcs(data)
+		isok(t, err)
+		if vcs != want {
+			t.Fatalf("Parsing %q got %#v, want %#v", data, vcs, want)
+		}
+		assert(t, vcs.String() == data)
+	}
+
+	_, err := control.ParseVcs("https://example.org/hello.git -b")
+	notok(t, err)
+	_, err = control.ParseVcs("https://example.org/hello.git [po")
+	notok(t, err)
+}
+
+func TestDSCFullFields(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader(`Format: 3.0 (quilt)
+Source: hello
+Binary: hello
+Architecture: any
+Version: 2.10-3
+Maintainer: Santiago Vila <sanvila@debian.org>
+Standards-Version: 4.6.2
+Vcs-Browser: https://salsa.debian.org/sanvila/hello
+Vcs-Git: https://salsa.debian.org/sanvila/hello.git -b debian/latest
+Testsuite: autopkgtest, autopkgtest-pkg-c
+Testsuite-Triggers: make, gcc
+Dgit: 0123456789abcdef0123456789abcdef01234567 debian archive/debian/2.10-3 https://git.dgit.debian.org/hello
+Build-Depends: debhelper-compat (= 13)
+Build-Conflicts: autoconf2.13
+Build-Conflicts-Indep: texlive
+Checksums-Sha512:
+ deadbeef 12 hello_2.10.orig.tar.gz
+Files:
+ cafebabe 12 hello_2.10.orig.tar.gz
+`))
+	dsc, err := control.ParseDsc(reader, "")
+	isok(t, err)
+
+	assert(t, dsc.StandardsVersion == "4.6.2")
+	assert(t, dsc.VcsBrowser == "https://salsa.debian.org/sanvila/hello")
+	assert(t, dsc.VcsGit.URL == "https://salsa.debian.org/sanvila/hello.git")
+	assert(t, dsc.VcsGit.Branch == "debian/latest")
+	assert(t, len(dsc.Testsuite) == 2 && dsc.Testsuite[1] == "autopkgtest-pkg-c")
+	assert(t, len(dsc.TestsuiteTriggers) == 2 && dsc.TestsuiteTriggers[0] == "make")
+	assert(t, dsc.Dgit.Commit == "0123456789abcdef0123456789abcdef01234567")
+	assert(t, dsc.Dgit.Ref == "archive/debian/2.10-3")
+	assert(t, len(dsc.BuildConflicts.Relations) == 1)
+	assert(t, dsc.BuildConflicts.Relations[0].Possibilities[0].Name == "autoconf2.13")
+	assert(t, len(dsc.BuildConflictsIndep.Relations) == 1)
+	assert(t, len(dsc.BuildConflictsArch.Relations) == 0)
+	assert(t, len(dsc.ChecksumsSha512) == 1)
+	assert(t, dsc.ChecksumsSha512[0].Algorithm == "sha512")
+
+	name, vcs, ok := dsc.Vcs()
+	assert(t, ok)
+	assert(t, name == "git")
+	assert(t, vcs.Branch == "debian/latest")
+
+	out := bytes.Buffer{}
+	isok(t, control.Marshal(&out, dsc))
+	assert(t, strings.Contains(out.String(), "Vcs-Git: https://salsa.debian.org/sanvila/hello.git -b debian/latest\n"))
+	assert(t, strings.Contains(out.String(), "Dgit: 0123456789abcdef0123456789abcdef01234567 debian archive/debian/2.10-3 https://git.dgit.debian.org/hello\n"))
+	assert(t, strings.Contains(out.String(), "Testsuite: autopkgtest, autopkgtest-pkg-c\nTestsuite-Triggers: make, gcc\n"))
+	assert(t, !strings.Contains(out.String(), "Vcs-Hg"))
+}
@@ -0,0 +1,196 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"pault.ag/go/debian/hashio"
+)
+
+// File verification {{{
+
+// Errors found by DSC.Verify and Changes.Verify. Each problem is reported as
+// a FileError, so the kind of problem can be checked with errors.Is.
+var (
+	ErrFileMissing           = errors.New("File is missing")
+	ErrFileSizeMismatch      = errors.New("File size mismatch")
+	ErrFileHashMismatch      = errors.New("File hash mismatch")
+	ErrChecksumsInconsistent = errors.New("Checksums fields are inconsistent")
+)
+
+// A FileError is a single problem with a file referenced by a .dsc or
+// .changes file. Algorithm is set if the problem is with one checksum
+// field in particular.
+type FileError struct {
+	Filename  string
+	Algorithm string
+	Err       error
+	Message   string
+}
+
+func (e *FileError) Error() string {
+	ret := e.Filename + ": " + e.Err.Error()
+	if e.Algorithm != "" {
+		ret += " (" + e.Algorithm + ")"
+	}
+	if e.Message != "" {
+		ret += ": " + e.Message
+	}
+	return ret
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// A VerifyError holds every problem found when verifying the files of a
+// .dsc or .changes file.
+type VerifyError struct {
+	Errors []*FileError
+}
+
+func (e *VerifyError) Error() string {
+	messages := []string{}
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *VerifyError) Unwrap() []error {
+	ret := []error{}
+	for _, err := range e.Errors {
+		ret = append(ret, err)
+	}
+	return ret
+}
+
+// Check that each set of checksums lists the same files, with the same
+// sizes, as the first set (the Files field), then hash every file in
+// baseDir once, checking it against all of its checksums.
+func verifyFiles(baseDir string, fields ...[]FileHash) error {
+	problems := []*FileError{}
+	problem := func(filename, algorithm string, err error, format string, args ...interface{}) {
+		problems = append(problems, &FileError{
+			Filename:  path.Join(baseDir, filename),
+			Algorithm: algorithm,
+			Err:       err,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+
+	/* Cross-check the checksum fields against the Files field */
+	reference := map[string]int64{}
+	for _, hash := range fields[0] {
+		reference[hash.Filename] = hash.Size
+	}
+	for _, field := range fields[1:] {
+		if len(field) == 0 {
+			continue
+		}
+		algorithm := field[0].Algorithm
+		seen := map[string]bool{}
+		for _, hash := range field {
+			seen[hash.Filename] = true
+			size, ok := reference[hash.Filename]
+			switch {
+			case !ok:
+				problem(hash.Filename, algorithm, ErrChecksumsInconsistent, "not listed in Files")
+			case size != hash.Size:
+				problem(hash.Filename, algorithm, ErrChecksumsInconsistent,
+					"size is %d, but %d in Files", hash.Size, size)
+			}
+		}
+		for _, hash := range fields[0] {
+			if !seen[hash.Filename] {
+				problem(hash.Filename, algorithm, ErrChecksumsInconsistent, "listed in Files only")
+			}
+		}
+	}
+
+	/* Group the checksums by file, keeping the order they were listed in */
+	order := []string{}
+	byFile := map[string][]FileHash{}
+	for _, field := range fields {
+		for _, hash := range field {
+			if _, ok := byFile[hash.Filename]; !ok {
+				order = append(order, hash.Filename)
+			}
+			byFile[hash.Filename] = append(byFile[hash.Filename], hash)
+		}
+	}
+
+	for _, filename := range order {
+		hashes := byFile[filename]
+
+		fd, err := os.Open(path.Join(baseDir, filename))
+		if os.IsNotExist(err) {
+			problem(filename, "", ErrFileMissing, "")
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		algorithms := []string{}
+		for _, hash := range hashes {
+			algorithms = append(algorithms, hash.Algorithm)
+		}
+		writer, hashers, err := hashio.NewHasherWriters(algorithms, ioutil.Discard)
+		if err != nil {
+			fd.Close()
+			return err
+		}
+		_, err = io.Copy(writer, fd)
+		fd.Close()
+		if err != nil {
+			return err
+		}
+
+		size := hashers[0].Size()
+		reported := map[int64]bool{}
+		for i, hash := range hashes {
+			if hash.Size != size && !reported[hash.Size] {
+				reported[hash.Size] = true
+				problem(filename, "", ErrFileSizeMismatch, "expected %d, got %d", hash.Size, size)
+			}
+			sum := fmt.Sprintf("%x", hashers[i].Sum(nil))
+			if !strings.EqualFold(sum, hash.Hash) {
+				problem(filename, hash.Algorithm, ErrFileHashMismatch, "expected %s, got %s", hash.Hash, sum)
+			}
+		}
+	}
+
+	if len(problems) != 0 {
+		return &VerifyError{Errors: problems}
+	}
+	return nil
+}
+
+// }}}
+
+// vim: foldmethod=marker
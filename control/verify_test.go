@@ -0,0 +1,115 @@
+package control_test
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+func verifyFixture(t *testing.T) *control.DSC {
+	dir := t.TempDir()
+	dsc := control.DSC{Filename: filepath.Join(dir, "hello_1.0-1.dsc")}
+	for name, data := range map[string]string{
+		"hello_1.0.orig.tar.gz":     "upstream",
+		"hello_1.0-1.debian.tar.xz": "packaging",
+	} {
+		isok(t, os.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+		size := int64(len(data))
+		dsc.Files = append(dsc.Files, control.MD5FileHash{FileHash: control.FileHash{
+			Algorithm: "md5", Filename: name, Size: size,
+			Hash: fmt.Sprintf("%x", md5.Sum([]byte(data))),
+		}})
+		dsc.ChecksumsSha1 = append(dsc.ChecksumsSha1, control.SHA1FileHash{FileHash: control.FileHash{
+			Algorithm: "sha1", Filename: name, Size: size,
+			Hash: fmt.Sprintf("%x", sha1.Sum([]byte(data))),
+		}})
+		dsc.ChecksumsSha256 = append(dsc.ChecksumsSha256, control.SHA256FileHash{FileHash: control.FileHash{
+			Algorithm: "sha256", Filename: name, Size: size,
+			Hash: fmt.Sprintf("%x", sha256.Sum256([]byte(data))),
+		}})
+	}
+	return &dsc
+}
+
+func TestDSCVerify(t *testing.T) {
+	dsc := verifyFixture(t)
+	isok(t, dsc.Verify())
+}
+
+func TestDSCVerifyProblems(t *testing.T) {
+	dsc := verifyFixture(t)
+	dir := filepath.Dir(dsc.Filename)
+
+	/* Corrupt one file, remove the other */
+	isok(t, os.WriteFile(filepath.Join(dir, dsc.Files[0].Filename), []byte("corrupted!"), 0644))
+	isok(t, os.Remove(filepath.Join(dir, dsc.Files[1].Filename)))
+
+	err := dsc.Verify()
+	notok(t, err)
+	assert(t, errors.Is(err, control.ErrFileMissing))
+	assert(t, errors.Is(err, control.ErrFileSizeMismatch))
+	assert(t, errors.Is(err, control.ErrFileHashMismatch))
+	assert(t, !errors.Is(err, control.ErrChecksumsInconsistent))
+
+	verifyErr := &control.VerifyError{}
+	assert(t, errors.As(err, &verifyErr))
+	/* One size mismatch, three hash mismatches, and one missing file */
+	assert(t, len(verifyErr.Errors) == 5)
+
+	algorithms := map[string]bool{}
+	for _, problem := range verifyErr.Errors {
+		if errors.Is(problem, control.ErrFileHashMismatch) {
+			algorithms[problem.Algorithm] = true
+		}
+	}
+	assert(t, algorithms["md5"] && algorithms["sha1"] && algorithms["sha256"])
+}
+
+func TestDSCVerifyInconsistent(t *testing.T) {
+	dsc := verifyFixture(t)
+	dsc.ChecksumsSha256 = dsc.ChecksumsSha256[:1]
+	dsc.ChecksumsSha1[0].Size++
+
+	err := dsc.Verify()
+	notok(t, err)
+	assert(t, errors.Is(err, control.ErrChecksumsInconsistent))
+
+	verifyErr := &control.VerifyError{}
+	assert(t, errors.As(err, &verifyErr))
+	inconsistent := 0
+	for _, problem := range verifyErr.Errors {
+		if errors.Is(problem, control.ErrChecksumsInconsistent) {
+			inconsistent++
+		}
+	}
+	assert(t, inconsistent == 2)
+}
+
+func TestChangesVerify(t *testing.T) {
+	dsc := verifyFixture(t)
+	changes := control.Changes{
+		Filename:        filepath.Join(filepath.Dir(dsc.Filename), "hello_1.0-1_source.changes"),
+		ChecksumsSha1:   dsc.ChecksumsSha1,
+		ChecksumsSha256: dsc.ChecksumsSha256,
+	}
+	for _, hash := range dsc.Files {
+		changes.Files = append(changes.Files, control.FileListChangesFileHash{
+			FileHash:  hash.FileHash,
+			Component: "main",
+			Priority:  "optional",
+		})
+	}
+	isok(t, changes.Verify())
+
+	changes.ChecksumsSha256[1].Hash = "00"
+	err := changes.Verify()
+	notok(t, err)
+	assert(t, errors.Is(err, control.ErrFileHashMismatch))
+}
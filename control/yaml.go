@@ -0,0 +1,117 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package control // import "pault.ag/go/debian/control"
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"pault.ag/go/debian/internal"
+)
+
+// YAML {{{
+
+// MarshalYAML serializes a Struct (or a pointer to one) as a YAML mapping,
+// with the same schema (and key order) as MarshalJSON.
+func MarshalYAML(data interface{}) (*yaml.Node, error) {
+	encoded, err := MarshalJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return internal.JSONToYAML(encoded)
+}
+
+// UnmarshalYAML is the inverse of MarshalYAML; it reads a YAML mapping keyed
+// by control keys into the Struct `into` points to, the same way
+// UnmarshalJSON does.
+func UnmarshalYAML(into interface{}, node *yaml.Node) error {
+	data, err := internal.YAMLToJSON(node)
+	if err != nil {
+		return err
+	}
+	return UnmarshalJSON(into, data)
+}
+
+// }}}
+
+// YAML methods {{{
+
+// The Paragraph types in this package all use MarshalYAML and UnmarshalYAML
+// for their YAML representation.
+
+func (d DSC) MarshalYAML() (interface{}, error) {
+	return MarshalYAML(&d)
+}
+
+func (d *DSC) UnmarshalYAML(node *yaml.Node) error {
+	return UnmarshalYAML(d, node)
+}
+
+func (c Changes) MarshalYAML() (interface{}, error) {
+	return MarshalYAML(&c)
+}
+
+func (c *Changes) UnmarshalYAML(node *yaml.Node) error {
+	return UnmarshalYAML(c, node)
+}
+
+func (b BinaryIndex) MarshalYAML() (interface{}, error) {
+	return MarshalYAML(&b)
+}
+
+func (b *BinaryIndex) UnmarshalYAML(node *yaml.Node) error {
+	return UnmarshalYAML(b, node)
+}
+
+func (s SourceIndex) MarshalYAML() (interface{}, error) {
+	return MarshalYAML(&s)
+}
+
+func (s *SourceIndex) UnmarshalYAML(node *yaml.Node) error {
+	return UnmarshalYAML(s, node)
+}
+
+func (s SourceParagraph) MarshalYAML() (interface{}, error) {
+	return MarshalYAML(&s)
+}
+
+func (s *SourceParagraph) UnmarshalYAML(node *yaml.Node) error {
+	return UnmarshalYAML(s, node)
+}
+
+func (r Release) MarshalYAML() (interface{}, error) {
+	return MarshalYAML(&r)
+}
+
+func (r *Release) UnmarshalYAML(node *yaml.Node) error {
+	return UnmarshalYAML(r, node)
+}
+
+func (b BinaryParagraph) MarshalYAML() (interface{}, error) {
+	return MarshalYAML(&b)
+}
+
+func (b *BinaryParagraph) UnmarshalYAML(node *yaml.Node) error {
+	return UnmarshalYAML(b, node)
+}
+
+// }}}
+
+// vim: foldmethod=marker
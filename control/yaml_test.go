@@ -0,0 +1,64 @@
+package control_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"pault.ag/go/debian/control"
+)
+
+func TestDSCYAMLRoundTrip(t *testing.T) {
+	// Test DSC {{{
+	reader := bufio.NewReader(strings.NewReader(`Format: 3.0 (quilt)
+Source: fbautostart
+Binary: fbautostart
+Architecture: any
+Version: 1:2.718281828-1
+Maintainer: Paul Tagliamonte <paultag@ubuntu.com>
+Build-Depends: debhelper (>= 9), foo [amd64] | bar <!nocheck>
+X-Extra: yes
+Files:
+ 06495f9b23b1c9b1bf35c2346cb48f63 92748 fbautostart_2.718281828.orig.tar.gz
+`))
+	// }}}
+	dsc, err := control.ParseDsc(reader, "")
+	isok(t, err)
+
+	data, err := yaml.Marshal(dsc)
+	isok(t, err)
+	assert(t, strings.HasPrefix(string(data), "Format: 3.0 (quilt)\nSource: fbautostart\nBinary:\n    - fbautostart\n"))
+	assert(t, strings.Contains(string(data), "\nVersion: 1:2.718281828-1\n"))
+	assert(t, strings.Contains(string(data), "\n      size: 92748\n"))
+	assert(t, strings.HasSuffix(string(data), "\nX-Extra: yes\n"))
+
+	back := control.DSC{}
+	isok(t, yaml.Unmarshal(data, &back))
+	assert(t, back.Source == "fbautostart")
+	assert(t, back.Version.Epoch == 1)
+	assert(t, back.Maintainer.Email == "paultag@ubuntu.com")
+	assert(t, back.BuildDepends.String() == dsc.BuildDepends.String())
+	assert(t, back.Files[0].Size == 92748)
+	assert(t, back.Values["X-Extra"] == "yes")
+
+	again, err := yaml.Marshal(back)
+	isok(t, err)
+	assert(t, string(again) == string(data))
+
+	/* Keys are in the same order as they are in the JSON */
+	node, err := control.MarshalYAML(&back)
+	isok(t, err)
+	assert(t, node.Kind == yaml.MappingNode)
+	assert(t, node.Content[0].Value == "Format")
+	assert(t, node.Content[len(node.Content)-2].Value == "X-Extra")
+}
+
+func TestUnmarshalYAMLErrors(t *testing.T) {
+	dsc := control.DSC{}
+	notok(t, yaml.Unmarshal([]byte("Version: \"\"\n"), &dsc))
+	notok(t, yaml.Unmarshal([]byte("- Source: hello\n"), &dsc))
+}
+
+// vim: foldmethod=marker
@@ -29,6 +29,8 @@ import (
 	"path"
 	"strings"
 
+	"gopkg.in/yaml.v3"
+
 	"pault.ag/go/debian/control"
 	"pault.ag/go/debian/dependency"
 	"pault.ag/go/debian/version"
@@ -61,6 +63,31 @@ type Control struct {
 	Description   string `required:"true"`
 }
 
+// Control is marshaled to JSON using control.MarshalJSON, keyed by the
+// control field names.
+func (c Control) MarshalJSON() ([]byte, error) {
+	return control.MarshalJSON(&c)
+}
+
+func (c *Control) UnmarshalJSON(data []byte) error {
+	return control.UnmarshalJSON(c, data)
+}
+
+// Control is marshaled to YAML with the same schema as JSON, using
+// control.MarshalYAML.
+func (c Control) MarshalYAML() (interface{}, error) {
+	return control.MarshalYAML(&c)
+}
+
+func (c *Control) UnmarshalYAML(node *yaml.Node) error {
+	return control.UnmarshalYAML(c, node)
+}
+
+// Parse the Description of this package.
+func (c Control) GetDescription() control.Description {
+	return control.ParseDescription(c.Description)
+}
+
 func (c Control) SourceName() string {
 	if c.Source == "" {
 		return c.Package
@@ -56,6 +56,11 @@ func (arch *Arch) UnmarshalControl(data string) error {
 	return parseArchInto(arch, data)
 }
 
+func (arch *Arch) UnmarshalText(text []byte) error {
+	*arch = Arch{ABI: "any", OS: "any", CPU: "any"}
+	return parseArchInto(arch, string(text))
+}
+
 func ParseArch(arch string) (*Arch, error) {
 	ret := &Arch{
 		ABI: "any",
@@ -92,7 +97,12 @@ func parseArchInto(ret *Arch, arch string) error {
 		}
 	case 2:
 		/* Right, this is something like kfreebsd-amd64, which is implicitly
-		 * gnu-kfreebsd-amd64 */
+		 * gnu-kfreebsd-amd64. Wildcards like linux-any or any-amd64 match
+		 * any ABI, though. */
+		ret.ABI = "gnu"
+		if flavors[0] == "any" || flavors[1] == "any" {
+			ret.ABI = "any"
+		}
 		ret.OS = flavors[0]
 		ret.CPU = flavors[1]
 	case 3:
@@ -36,6 +36,15 @@ func TestArchBasics(t *testing.T) {
 	assert(t, arch.CPU == "amd64")
 	assert(t, arch.ABI == "gnu")
 	assert(t, arch.OS == "linux")
+
+	arch, err = dependency.ParseArch("kfreebsd-amd64")
+	isok(t, err)
+	assert(t, arch.ABI == "gnu")
+	assert(t, !arch.IsWildcard())
+
+	arch, err = dependency.ParseArch("linux-any")
+	isok(t, err)
+	assert(t, arch.ABI == "any")
 }
 
 /*
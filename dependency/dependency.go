@@ -99,4 +99,23 @@ func (v VersionRelation) SatisfiedBy(ver version.Version) bool {
 	return false
 }
 
+// Return true if the StageSet (a single `<...>` group of a restriction
+// formula) is satisfied by the given active build profiles; that is, every
+// Stage is active, or inactive if it's negated.
+func (stageSet StageSet) Matches(profiles []string) bool {
+	for _, stage := range stageSet.Stages {
+		active := false
+		for _, profile := range profiles {
+			if profile == stage.Name {
+				active = true
+				break
+			}
+		}
+		if active == stage.Not {
+			return false
+		}
+	}
+	return true
+}
+
 // vim: foldmethod=marker
@@ -118,4 +118,21 @@ func TestVersionRelationSatisfiedBy(t *testing.T) {
 	}
 }
 
+func TestStageSetMatches(t *testing.T) {
+	dep, err := dependency.Parse("foo <!stage1 !nocheck> <stage2>")
+	isok(t, err)
+	stageSets := dep.Relations[0].Possibilities[0].StageSets
+	assert(t, len(stageSets) == 2)
+
+	assert(t, stageSets[0].Matches(nil))
+	assert(t, stageSets[0].Matches([]string{"nodoc"}))
+	assert(t, !stageSets[0].Matches([]string{"stage1"}))
+	assert(t, !stageSets[0].Matches([]string{"nodoc", "nocheck"}))
+
+	assert(t, !stageSets[1].Matches(nil))
+	assert(t, stageSets[1].Matches([]string{"stage2", "stage1"}))
+
+	assert(t, dependency.StageSet{}.Matches([]string{"stage1"}))
+}
+
 // vim: foldmethod=marker
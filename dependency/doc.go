@@ -11,5 +11,26 @@ Dependency relationships.
            | Version       | -> Version             (>= 1.0)
            | Architectures | -> Arch                          amd64
            | Stages        |
+
+When marshaled as JSON, a Dependency is written as a tree rather than as a
+string:
+
+  {"relations": [
+    {"possibilities": [{"name": "foo"}]},
+    {"possibilities": [
+      {"name": "bar",
+       "version": {"operator": ">=", "number": "1.0"},
+       "architectures": {"architectures": ["amd64"]}},
+      {"name": "baz"}
+    ]}
+  ]}
+
+Possibilities may also have "arch" (a string, such as "any"), "stageSets"
+(a list of {"stages": [{"not": true, "name": "nocheck"}]}) and
+"substvar" keys. An Arch on its own is written as a string, such as
+"amd64" or "kfreebsd-any". Keys with empty values are left out.
+
+A Dependency (and each Relation and Possibility) has the same schema when
+marshaled as YAML, using gopkg.in/yaml.v3.
 */
 package dependency // import "pault.ag/go/debian/dependency"
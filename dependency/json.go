@@ -0,0 +1,59 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package dependency // import "pault.ag/go/debian/dependency"
+
+import (
+	"encoding/json"
+)
+
+// plainPossibility has the same fields as Possibility, without the JSON
+// methods, so that encoding/json can do the actual work.
+type plainPossibility Possibility
+
+// The parser always gives a Possibility an (often empty) ArchSet, which
+// is left out of the JSON if it has no Architectures.
+func (possi Possibility) MarshalJSON() ([]byte, error) {
+	plain := plainPossibility(possi)
+	if plain.Architectures != nil && len(plain.Architectures.Architectures) == 0 {
+		plain.Architectures = nil
+	}
+	return json.Marshal(plain)
+}
+
+// Read a Possibility from JSON, setting up the empty ArchSet and StageSets
+// the same way the parser does, so that it's safe to use a Possibility
+// read from JSON anywhere a parsed one could be used.
+func (possi *Possibility) UnmarshalJSON(data []byte) error {
+	plain := plainPossibility{
+		Architectures: &ArchSet{Architectures: []Arch{}},
+		StageSets:     []StageSet{},
+	}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return err
+	}
+	if plain.Architectures == nil {
+		plain.Architectures = &ArchSet{Architectures: []Arch{}}
+	}
+	*possi = Possibility(plain)
+	return nil
+}
+
+// vim: foldmethod=marker
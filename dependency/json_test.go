@@ -0,0 +1,39 @@
+package dependency_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"pault.ag/go/debian/dependency"
+)
+
+func TestDependencyJSONRoundTrip(t *testing.T) {
+	dep, err := dependency.Parse("foo [amd64 kfreebsd-any] <!nocheck>, bar (>= 1.0) | baz:any")
+	isok(t, err)
+
+	data, err := json.Marshal(dep)
+	isok(t, err)
+
+	back := dependency.Dependency{}
+	isok(t, json.Unmarshal(data, &back))
+	assert(t, back.String() == dep.String())
+	assert(t, len(back.Relations) == 2)
+	assert(t, back.Relations[0].Possibilities[0].Architectures.Architectures[1].OS == "kfreebsd")
+	assert(t, back.Relations[1].Possibilities[0].Version.Number == "1.0")
+	assert(t, back.Relations[1].Possibilities[1].Arch.CPU == "any")
+
+	/* No ArchSet in the JSON, but it's still safe to use */
+	assert(t, back.Relations[1].Possibilities[0].Architectures != nil)
+}
+
+func TestArchJSON(t *testing.T) {
+	arches := []dependency.Arch{}
+	isok(t, json.Unmarshal([]byte(`["amd64", "kfreebsd-i386", "all"]`), &arches))
+	assert(t, arches[0].CPU == "amd64")
+	assert(t, arches[1].OS == "kfreebsd")
+	assert(t, arches[2].CPU == "all")
+
+	data, err := json.Marshal(arches)
+	isok(t, err)
+	assert(t, string(data) == `["amd64","kfreebsd-i386","all"]`)
+}
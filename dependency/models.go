@@ -26,8 +26,8 @@ package dependency // import "pault.ag/go/debian/dependency"
 // restrict the relation to one some architectures. This is also usually
 // used in a string of many possibilities.
 type ArchSet struct {
-	Not           bool
-	Architectures []Arch
+	Not           bool   `json:"not,omitempty"`
+	Architectures []Arch `json:"architectures"`
 }
 
 // VersionRelation models a version restriction on a possibility, such as
@@ -39,17 +39,17 @@ type ArchSet struct {
 //   respectively.
 //
 type VersionRelation struct {
-	Number   string
-	Operator string
+	Number   string `json:"number"`
+	Operator string `json:"operator"`
 }
 
 type Stage struct {
-	Not  bool
-	Name string
+	Not  bool   `json:"not,omitempty"`
+	Name string `json:"name"`
 }
 
 type StageSet struct {
-	Stages []Stage
+	Stages []Stage `json:"stages"`
 }
 
 // Possibility models a concrete Possibility that may be satisfied in order
@@ -62,12 +62,12 @@ type StageSet struct {
 // Build Stage.
 //
 type Possibility struct {
-	Name          string
-	Arch          *Arch
-	Architectures *ArchSet
-	StageSets     []StageSet
-	Version       *VersionRelation
-	Substvar      bool
+	Name          string           `json:"name"`
+	Arch          *Arch            `json:"arch,omitempty"`
+	Architectures *ArchSet         `json:"architectures,omitempty"`
+	StageSets     []StageSet       `json:"stageSets,omitempty"`
+	Version       *VersionRelation `json:"version,omitempty"`
+	Substvar      bool             `json:"substvar,omitempty"`
 }
 
 // }}}
@@ -80,12 +80,12 @@ type Possibility struct {
 // There are two Relations, one composed of foo, and another composed of
 // bar and baz.
 type Relation struct {
-	Possibilities []Possibility
+	Possibilities []Possibility `json:"possibilities"`
 }
 
 // A Dependency is the top level type that models a full Dependency relation.
 type Dependency struct {
-	Relations []Relation
+	Relations []Relation `json:"relations"`
 }
 
 func (dep *Dependency) UnmarshalControl(data string) error {
@@ -28,6 +28,12 @@ func (a Arch) MarshalControl() (string, error) {
 	return a.String(), nil
 }
 
+// Arch is written out as its string form (such as `amd64`, or
+// `kfreebsd-any`) when marshaled as text, JSON or YAML.
+func (a Arch) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
 func (a Arch) String() string {
 	/* ABI-OS-CPU -- gnu-linux-amd64 */
 	els := []string{}
@@ -35,7 +41,13 @@ func (a Arch) String() string {
 		els = append(els, a.ABI)
 	}
 
-	if a.OS != "any" && a.OS != "all" && a.OS != "linux" {
+	/* linux is implied for a concrete CPU (amd64), but not for a
+	 * wildcard one (linux-any). A wildcard OS has to be kept for a
+	 * concrete CPU, since any-amd64 matches more than amd64 does. */
+	switch {
+	case a.OS == "any" && a.CPU != "any" && a.CPU != "all":
+		els = append(els, a.OS)
+	case a.OS != "any" && a.OS != "all" && (a.OS != "linux" || a.CPU == "any"):
 		els = append(els, a.OS)
 	}
 
@@ -33,6 +33,10 @@ func TestArchString(t *testing.T) {
 		"amd64":            "amd64",
 		"gnu-linux-amd64":  "amd64",
 		"bsd-windows-i386": "bsd-windows-i386",
+		"linux-any":        "linux-any",
+		"kfreebsd-any":     "kfreebsd-any",
+		"any-amd64":        "any-amd64",
+		"any-i386":         "any-i386",
 	}
 
 	for _, el := range equivs {
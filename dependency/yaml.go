@@ -0,0 +1,75 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package dependency // import "pault.ag/go/debian/dependency"
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v3"
+
+	"pault.ag/go/debian/internal"
+)
+
+// A Dependency, and the Relations and Possibilities in it, have the same
+// schema in YAML as they do in JSON. An Arch is written as its string form,
+// by way of MarshalText.
+
+func (dep Dependency) MarshalYAML() (interface{}, error) {
+	return marshalYAML(dep)
+}
+
+func (dep *Dependency) UnmarshalYAML(node *yaml.Node) error {
+	return unmarshalYAML(node, dep)
+}
+
+func (rel Relation) MarshalYAML() (interface{}, error) {
+	return marshalYAML(rel)
+}
+
+func (rel *Relation) UnmarshalYAML(node *yaml.Node) error {
+	return unmarshalYAML(node, rel)
+}
+
+func (possi Possibility) MarshalYAML() (interface{}, error) {
+	return marshalYAML(possi)
+}
+
+func (possi *Possibility) UnmarshalYAML(node *yaml.Node) error {
+	return unmarshalYAML(node, possi)
+}
+
+func marshalYAML(value interface{}) (*yaml.Node, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return internal.JSONToYAML(data)
+}
+
+func unmarshalYAML(node *yaml.Node, into interface{}) error {
+	data, err := internal.YAMLToJSON(node)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
+
+// vim: foldmethod=marker
@@ -0,0 +1,51 @@
+package dependency_test
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"pault.ag/go/debian/dependency"
+)
+
+func TestDependencyYAMLRoundTrip(t *testing.T) {
+	dep, err := dependency.Parse("foo [amd64 kfreebsd-any] <!nocheck>, bar (>= 1.0) | baz:any")
+	isok(t, err)
+
+	data, err := yaml.Marshal(dep)
+	isok(t, err)
+
+	generic := map[string]interface{}{}
+	isok(t, yaml.Unmarshal(data, &generic))
+	relations := generic["relations"].([]interface{})
+	assert(t, len(relations) == 2)
+	bar := relations[1].(map[string]interface{})["possibilities"].([]interface{})[0].(map[string]interface{})
+	assert(t, bar["name"] == "bar")
+	assert(t, bar["version"].(map[string]interface{})["number"] == "1.0")
+	_, ok := bar["architectures"]
+	assert(t, !ok)
+
+	back := dependency.Dependency{}
+	isok(t, yaml.Unmarshal(data, &back))
+	assert(t, back.String() == dep.String())
+	assert(t, back.Relations[0].Possibilities[0].Architectures.Architectures[1].OS == "kfreebsd")
+	assert(t, back.Relations[1].Possibilities[1].Arch.CPU == "any")
+	assert(t, back.Relations[1].Possibilities[0].Architectures != nil)
+
+	possi := dependency.Possibility{}
+	isok(t, yaml.Unmarshal([]byte("name: foo\nstageSets:\n  - stages:\n      - not: true\n        name: nocheck\n"), &possi))
+	assert(t, possi.Name == "foo")
+	assert(t, possi.StageSets[0].Stages[0].Not)
+}
+
+func TestArchYAML(t *testing.T) {
+	arches := []dependency.Arch{}
+	isok(t, yaml.Unmarshal([]byte("[amd64, kfreebsd-i386, all]"), &arches))
+	assert(t, arches[0].CPU == "amd64")
+	assert(t, arches[1].OS == "kfreebsd")
+	assert(t, arches[2].CPU == "all")
+
+	data, err := yaml.Marshal(arches)
+	isok(t, err)
+	assert(t, string(data) == "- amd64\n- kfreebsd-i386\n- all\n")
+}
@@ -0,0 +1,193 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package dpkg // import "pault.ag/go/debian/dpkg"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pault.ag/go/debian/control"
+)
+
+// Database {{{
+
+// Database is the dpkg database of a filesystem rooted at Root, such as
+// `/` for the running system, or the directory an image was unpacked into.
+type Database struct {
+	// The root of the filesystem; paths in the database are relative to
+	// this directory.
+	Root string
+
+	// Every entry in the status file, in the order they were read.
+	Packages []Package
+}
+
+// The path to the dpkg admin directory, relative to the root.
+const AdminDir = "var/lib/dpkg"
+
+// Open {{{
+
+// Open the dpkg database of the filesystem rooted at `root`, reading in
+// the status file. The files in the info directory are read as they're
+// asked for.
+func Open(root string) (*Database, error) {
+	db := Database{Root: root}
+	packages, err := ParseStatusFile(db.adminPath("status"))
+	if err != nil {
+		return nil, err
+	}
+	db.Packages = packages
+	return &db, nil
+}
+
+// Read in the available file of this Database. This file is only kept
+// up to date by some frontends, and is often empty or missing.
+func (db *Database) Available() ([]Package, error) {
+	return ParseStatusFile(db.adminPath("available"))
+}
+
+func (db *Database) adminPath(parts ...string) string {
+	return filepath.Join(append([]string{db.Root, AdminDir}, parts...)...)
+}
+
+// }}}
+
+// Package lookups {{{
+
+// Return the entries for the package with the given name. The name may be
+// qualified with an architecture (`libc6:amd64`), in which case only the
+// entry for that architecture is returned. More than one entry may be
+// returned for a Multi-Arch: same package.
+func (db *Database) Lookup(name string) []*Package {
+	name, arch, qualified := strings.Cut(name, ":")
+	ret := []*Package{}
+	for i := range db.Packages {
+		pkg := &db.Packages[i]
+		if pkg.Package != name {
+			continue
+		}
+		if qualified && arch != pkg.Architecture.String() {
+			continue
+		}
+		ret = append(ret, pkg)
+	}
+	return ret
+}
+
+// }}}
+
+// Info files {{{
+
+// Open the file in the info directory for the given package with the
+// given extension (such as `list`). dpkg names these files after the
+// package, qualified with the architecture for Multi-Arch: same packages;
+// since older versions of dpkg didn't, the unqualified name is tried too.
+func (db *Database) openInfo(pkg *Package, extension string) (*os.File, error) {
+	names := []string{pkg.InfoName()}
+	if names[0] != pkg.Package {
+		names = append(names, pkg.Package)
+	}
+
+	var err error
+	for _, name := range names {
+		var fd *os.File
+		fd, err = os.Open(db.adminPath("info", name+"."+extension))
+		if err == nil {
+			return fd, nil
+		}
+	}
+	return nil, err
+}
+
+// Read the lines of an info file, skipping blank lines. A missing file is
+// treated as being empty, since dpkg doesn't write out empty info files.
+func (db *Database) readInfo(pkg *Package, extension string) ([]string, error) {
+	fd, err := db.openInfo(pkg, extension)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return readLines(fd)
+}
+
+// Return the paths installed by the given package, from its `.list` file
+// in the info directory. This includes the directories the package
+// created, and is in the order dpkg wrote it.
+func (db *Database) Files(pkg *Package) ([]string, error) {
+	return db.readInfo(pkg, "list")
+}
+
+// Return the md5sums of the files installed by the given package, from its
+// `.md5sums` file in the info directory. The Filename of each FileHash is
+// the absolute path of the file (relative to the root).
+func (db *Database) MD5Sums(pkg *Package) ([]control.FileHash, error) {
+	lines, err := db.readInfo(pkg, "md5sums")
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []control.FileHash{}
+	for _, line := range lines {
+		hash, path, found := strings.Cut(line, " ")
+		if !found {
+			return nil, fmt.Errorf("Malformed md5sums line for %s: '%s'", pkg.Package, line)
+		}
+		path = strings.TrimLeft(path, " *")
+		ret = append(ret, control.FileHash{
+			Algorithm: "md5",
+			Hash:      hash,
+			Filename:  "/" + strings.TrimPrefix(path, "/"),
+		})
+	}
+	return ret, nil
+}
+
+// Return the conffiles shipped by the given package, from its `.conffiles`
+// file in the info directory. Since that file doesn't have the hashes, only
+// the Path (and RemoveOnUpgrade) of each Conffile is set; the Conffiles
+// field of the Package has the hashes dpkg recorded on install.
+func (db *Database) Conffiles(pkg *Package) ([]Conffile, error) {
+	lines, err := db.readInfo(pkg, "conffiles")
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []Conffile{}
+	for _, line := range lines {
+		conffile := Conffile{Path: strings.TrimSpace(line)}
+		if path, ok := strings.CutPrefix(conffile.Path, "remove-on-upgrade "); ok {
+			conffile.Path = strings.TrimSpace(path)
+			conffile.RemoveOnUpgrade = true
+		}
+		ret = append(ret, conffile)
+	}
+	return ret, nil
+}
+
+// }}}
+
+// }}}
+
+// vim: foldmethod=marker
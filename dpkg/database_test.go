@@ -0,0 +1,83 @@
+package dpkg_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pault.ag/go/debian/dpkg"
+)
+
+// Write out a tiny dpkg database under a temporary root, and return the
+// root.
+func makeRoot(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for path, data := range files {
+		path = filepath.Join(root, dpkg.AdminDir, path)
+		isok(t, os.MkdirAll(filepath.Dir(path), 0755))
+		isok(t, os.WriteFile(path, []byte(data), 0644))
+	}
+	return root
+}
+
+func TestDatabase(t *testing.T) {
+	root := makeRoot(t, map[string]string{
+		"status": statusFile,
+		"info/base-files.list": `/.
+/etc
+/etc/debian_version
+/etc/host.conf
+`,
+		"info/base-files.md5sums": `4a1f2bd5f1a9a2ea6a2d1e4d0a37f8f6  etc/debian_version
+`,
+		"info/base-files.conffiles": `/etc/debian_version
+remove-on-upgrade /etc/issue
+`,
+		"info/libc6:amd64.list": "/lib/x86_64-linux-gnu/libc.so.6\n",
+	})
+
+	db, err := dpkg.Open(root)
+	isok(t, err)
+	assert(t, len(db.Packages) == 3)
+
+	base := db.Lookup("base-files")
+	assert(t, len(base) == 1)
+	assert(t, len(db.Lookup("base-files:i386")) == 0)
+
+	files, err := db.Files(base[0])
+	isok(t, err)
+	assert(t, len(files) == 4)
+	assert(t, files[2] == "/etc/debian_version")
+
+	sums, err := db.MD5Sums(base[0])
+	isok(t, err)
+	assert(t, len(sums) == 1)
+	assert(t, sums[0].Filename == "/etc/debian_version")
+	assert(t, sums[0].Hash == "4a1f2bd5f1a9a2ea6a2d1e4d0a37f8f6")
+
+	conffiles, err := db.Conffiles(base[0])
+	isok(t, err)
+	assert(t, len(conffiles) == 2)
+	assert(t, !conffiles[0].RemoveOnUpgrade)
+	assert(t, conffiles[1].Path == "/etc/issue")
+	assert(t, conffiles[1].RemoveOnUpgrade)
+
+	libc := db.Lookup("libc6:amd64")
+	assert(t, len(libc) == 1)
+	files, err = db.Files(libc[0])
+	isok(t, err)
+	assert(t, len(files) == 1)
+
+	/* No info files at all */
+	files, err = db.Files(db.Lookup("oldpkg")[0])
+	isok(t, err)
+	assert(t, len(files) == 0)
+
+	_, err = db.Available()
+	notok(t, err)
+}
+
+func TestOpenMissing(t *testing.T) {
+	_, err := dpkg.Open(t.TempDir())
+	notok(t, err)
+}
@@ -0,0 +1,26 @@
+/*
+
+Read and write the dpkg database, as found in `/var/lib/dpkg`.
+
+The status file (and the available file, which has the same format) is
+read into a list of Package entries, each of which has its `Status` field
+split into the desired action, the error flag and the package state. The
+per-package files in the `info` directory (`*.list`, `*.md5sums` and
+`*.conffiles`) can be loaded from a Database, which may be rooted at any
+directory, such as an unpacked container image, without running dpkg.
+
+Here's a trivial example, which lists the installed packages in a
+container filesystem:
+
+	db, err := dpkg.Open("/srv/rootfs")
+	if err != nil {
+		panic(err)
+	}
+	for _, pkg := range db.Packages {
+		if pkg.Status.State == dpkg.StateInstalled {
+			fmt.Printf("%s %s\n", pkg.Package, pkg.Version)
+		}
+	}
+
+*/
+package dpkg // import "pault.ag/go/debian/dpkg"
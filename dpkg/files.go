@@ -0,0 +1,317 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package dpkg // import "pault.ag/go/debian/dpkg"
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Diversions {{{
+
+// Diversion is a single entry of the dpkg diversions file; a path that
+// dpkg will install somewhere else (To) whenever a package other than
+// the diverting Package ships it.
+type Diversion struct {
+	From string
+	To   string
+
+	// The package that set up the diversion, or ":" for a local
+	// diversion made by the administrator.
+	Package string
+}
+
+// Return true if this diversion was made by the administrator, rather
+// than by a package.
+func (d Diversion) Local() bool {
+	return d.Package == ":"
+}
+
+// Parse the dpkg diversions file, which is made up of groups of three
+// lines; the diverted path, where it was diverted to, and the package
+// that made the diversion.
+func ParseDiversions(reader io.Reader) ([]Diversion, error) {
+	lines, err := readLines(reader)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines)%3 != 0 {
+		return nil, fmt.Errorf("Diversions file has %d lines, which isn't a multiple of three", len(lines))
+	}
+
+	ret := []Diversion{}
+	for i := 0; i < len(lines); i += 3 {
+		ret = append(ret, Diversion{
+			From:    lines[i],
+			To:      lines[i+1],
+			Package: lines[i+2],
+		})
+	}
+	return ret, nil
+}
+
+// Read in the diversions file of this Database. If there is no diversions
+// file, there are no diversions.
+func (db *Database) Diversions() ([]Diversion, error) {
+	fd, err := os.Open(db.adminPath("diversions"))
+	if os.IsNotExist(err) {
+		return []Diversion{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return ParseDiversions(fd)
+}
+
+// }}}
+
+// Stat overrides {{{
+
+// StatOverride is a single entry of the dpkg statoverride file, which
+// changes the owner and mode dpkg gives a path when it's installed.
+type StatOverride struct {
+	User  string
+	Group string
+	Mode  os.FileMode
+	Path  string
+}
+
+// Parse the dpkg statoverride file, which has one `user group mode path`
+// entry per line. The mode is in octal.
+func ParseStatOverrides(reader io.Reader) ([]StatOverride, error) {
+	lines, err := readLines(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []StatOverride{}
+	for _, line := range lines {
+		parts := strings.SplitN(line, " ", 4)
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("Malformed statoverride line: '%s'", line)
+		}
+		mode, err := strconv.ParseUint(parts[2], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Bad mode in statoverride line: '%s'", line)
+		}
+		ret = append(ret, StatOverride{
+			User:  parts[0],
+			Group: parts[1],
+			Mode:  unixMode(mode),
+			Path:  parts[3],
+		})
+	}
+	return ret, nil
+}
+
+// Read in the statoverride file of this Database. If there is no
+// statoverride file, there are no overrides.
+func (db *Database) StatOverrides() ([]StatOverride, error) {
+	fd, err := os.Open(db.adminPath("statoverride"))
+	if os.IsNotExist(err) {
+		return []StatOverride{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return ParseStatOverrides(fd)
+}
+
+// Convert a Unix mode, as written by chmod, into an os.FileMode, which
+// keeps the setuid, setgid and sticky bits elsewhere.
+func unixMode(mode uint64) os.FileMode {
+	ret := os.FileMode(mode & 0777)
+	if mode&04000 != 0 {
+		ret |= os.ModeSetuid
+	}
+	if mode&02000 != 0 {
+		ret |= os.ModeSetgid
+	}
+	if mode&01000 != 0 {
+		ret |= os.ModeSticky
+	}
+	return ret
+}
+
+// Read all the non-blank lines of the given reader.
+func readLines(reader io.Reader) ([]string, error) {
+	ret := []string{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		ret = append(ret, line)
+	}
+	return ret, scanner.Err()
+}
+
+// }}}
+
+// FileIndex {{{
+
+// FileIndex maps the paths installed on the filesystem back to the
+// packages that installed them, the same way `dpkg -S` and `dpkg -L` do,
+// taking diversions and stat overrides into account.
+type FileIndex struct {
+	files     map[*Package][]string
+	listed    map[string][]*Package
+	divertsTo map[string]*Diversion
+	diverted  map[string]*Diversion
+	overrides map[string]*StatOverride
+}
+
+// Build a FileIndex from the `.list` files of every package in the
+// Database that has files on disk (or left behind, for packages in the
+// config-files state), along with the diversions and statoverride files.
+func (db *Database) FileIndex() (*FileIndex, error) {
+	idx := FileIndex{
+		files:     map[*Package][]string{},
+		listed:    map[string][]*Package{},
+		divertsTo: map[string]*Diversion{},
+		diverted:  map[string]*Diversion{},
+		overrides: map[string]*StatOverride{},
+	}
+
+	for i := range db.Packages {
+		pkg := &db.Packages[i]
+		if !pkg.IsInstalled() && pkg.Status.State != StateConfigFiles {
+			continue
+		}
+
+		files, err := db.Files(pkg)
+		if err != nil {
+			return nil, err
+		}
+		for j, file := range files {
+			file = cleanPath(file)
+			files[j] = file
+			idx.listed[file] = append(idx.listed[file], pkg)
+		}
+		idx.files[pkg] = files
+	}
+
+	diversions, err := db.Diversions()
+	if err != nil {
+		return nil, err
+	}
+	for i := range diversions {
+		diversion := &diversions[i]
+		idx.diverted[cleanPath(diversion.From)] = diversion
+		idx.divertsTo[cleanPath(diversion.To)] = diversion
+	}
+
+	overrides, err := db.StatOverrides()
+	if err != nil {
+		return nil, err
+	}
+	for i := range overrides {
+		idx.overrides[cleanPath(overrides[i].Path)] = &overrides[i]
+	}
+
+	return &idx, nil
+}
+
+// The `.list` files name the root directory `/.`, and may otherwise have
+// paths in a form that doesn't match what the caller asks for.
+func cleanPath(file string) string {
+	return path.Clean("/" + file)
+}
+
+// Return the packages whose `.list` file has the given path, exactly as
+// `dpkg -S /path` would, without taking diversions into account.
+// Directories are often listed by more than one package.
+func (idx *FileIndex) Search(file string) []*Package {
+	return idx.listed[cleanPath(file)]
+}
+
+// Return the packages that installed whatever is on disk at the given
+// path, taking diversions into account:
+//
+//   - If the path is where a diversion sends a file, the file there was
+//     installed by the packages that list the original path, other than
+//     the package that made the diversion.
+//   - If the path has been diverted, the file there was installed by the
+//     package that made the diversion (if it ships that path at all).
+//   - Otherwise, it's whatever packages list the path.
+func (idx *FileIndex) Owners(file string) []*Package {
+	file = cleanPath(file)
+	ret := []*Package{}
+
+	if diversion, ok := idx.divertsTo[file]; ok {
+		for _, pkg := range idx.listed[cleanPath(diversion.From)] {
+			if pkg.Package != diversion.Package {
+				ret = append(ret, pkg)
+			}
+		}
+		return ret
+	}
+
+	if diversion, ok := idx.diverted[file]; ok {
+		for _, pkg := range idx.listed[file] {
+			if pkg.Package == diversion.Package {
+				ret = append(ret, pkg)
+			}
+		}
+		return ret
+	}
+
+	return append(ret, idx.listed[file]...)
+}
+
+// Return the paths the given package installed, with any diverted paths
+// replaced by where the file was put on disk; this is the list of files
+// `dpkg -L` shows, after following the "diverted by" notes.
+func (idx *FileIndex) Files(pkg *Package) []string {
+	ret := []string{}
+	for _, file := range idx.files[pkg] {
+		if diversion, ok := idx.diverted[file]; ok && diversion.Package != pkg.Package {
+			file = cleanPath(diversion.To)
+		}
+		ret = append(ret, file)
+	}
+	return ret
+}
+
+// Return the diversion that applies to the given path, either because the
+// path is diverted, or because it is where a diverted path goes.
+func (idx *FileIndex) Diversion(file string) *Diversion {
+	file = cleanPath(file)
+	if diversion, ok := idx.diverted[file]; ok {
+		return diversion
+	}
+	return idx.divertsTo[file]
+}
+
+// Return the stat override for the given path, if there is one.
+func (idx *FileIndex) StatOverride(file string) *StatOverride {
+	return idx.overrides[cleanPath(file)]
+}
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,97 @@
+package dpkg_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/dpkg"
+)
+
+const filesStatus = `Package: dash
+Status: install ok installed
+Architecture: amd64
+Version: 0.5.12-2
+
+Package: bash
+Status: install ok installed
+Architecture: amd64
+Version: 5.2.15-2
+
+Package: gone
+Status: purge ok not-installed
+Architecture: amd64
+Version: 1.0
+`
+
+func TestFileIndex(t *testing.T) {
+	root := makeRoot(t, map[string]string{
+		"status": filesStatus,
+		"info/dash.list": `/.
+/bin
+/bin/dash
+/usr/share/man/man1/sh.1.gz
+`,
+		"info/bash.list": `/.
+/bin
+/bin/bash
+/usr/share/man/man1/sh.1.gz
+`,
+		"info/gone.list": "/bin/gone\n",
+		"diversions": `/usr/share/man/man1/sh.1.gz
+/usr/share/man/man1/sh.distrib.1.gz
+dash
+/bin/bash
+/bin/bash.real
+:
+`,
+		"statoverride": "root crontab 2755 /usr/bin/crontab\n",
+	})
+
+	db, err := dpkg.Open(root)
+	isok(t, err)
+	idx, err := db.FileIndex()
+	isok(t, err)
+
+	assert(t, len(idx.Search("/bin")) == 2)
+	assert(t, len(idx.Search("/")) == 2)
+	assert(t, len(idx.Search("/bin/gone")) == 0)
+	assert(t, idx.Search("bin/dash")[0].Package == "dash")
+
+	/* dash diverted bash's manpage out of the way */
+	owners := idx.Owners("/usr/share/man/man1/sh.1.gz")
+	assert(t, len(owners) == 1 && owners[0].Package == "dash")
+	owners = idx.Owners("/usr/share/man/man1/sh.distrib.1.gz")
+	assert(t, len(owners) == 1 && owners[0].Package == "bash")
+
+	/* A local diversion; nobody installed the file that's there now */
+	assert(t, len(idx.Owners("/bin/bash")) == 0)
+	owners = idx.Owners("/bin/bash.real")
+	assert(t, len(owners) == 1 && owners[0].Package == "bash")
+	assert(t, idx.Diversion("/bin/bash.real").Local())
+	assert(t, idx.Diversion("/bin/dash") == nil)
+
+	bash := db.Lookup("bash")[0]
+	files := idx.Files(bash)
+	assert(t, strings.Join(files, " ") == "/ /bin /bin/bash.real /usr/share/man/man1/sh.distrib.1.gz")
+	dash := db.Lookup("dash")[0]
+	assert(t, idx.Files(dash)[3] == "/usr/share/man/man1/sh.1.gz")
+
+	override := idx.StatOverride("/usr/bin/crontab")
+	assert(t, override != nil)
+	assert(t, override.Group == "crontab")
+	assert(t, override.Mode == os.ModeSetgid|0755)
+	assert(t, idx.StatOverride("/bin/dash") == nil)
+}
+
+func TestParseDiversionsErrors(t *testing.T) {
+	_, err := dpkg.ParseDiversions(strings.NewReader("/a\n/b\n"))
+	notok(t, err)
+}
+
+func TestParseStatOverridesErrors(t *testing.T) {
+	_, err := dpkg.ParseStatOverrides(strings.NewReader("root root /bin/foo\n"))
+	notok(t, err)
+	_, err = dpkg.ParseStatOverrides(strings.NewReader("root root 999 /bin/foo\n"))
+	notok(t, err)
+}
@@ -0,0 +1,329 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package dpkg // import "pault.ag/go/debian/dpkg"
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/dependency"
+	"pault.ag/go/debian/version"
+)
+
+// Status {{{
+
+// Want is the action the user (or a frontend) asked dpkg to take on the
+// package, the first word of the Status field.
+type Want string
+
+const (
+	WantUnknown   Want = "unknown"
+	WantInstall   Want = "install"
+	WantHold      Want = "hold"
+	WantDeinstall Want = "deinstall"
+	WantPurge     Want = "purge"
+)
+
+// Flag is the error flag of the package, the second word of the Status
+// field.
+type Flag string
+
+const (
+	FlagOK        Flag = "ok"
+	FlagReinstReq Flag = "reinstreq"
+)
+
+// State is the state the package is in on the system, the third word of
+// the Status field.
+type State string
+
+const (
+	StateNotInstalled    State = "not-installed"
+	StateConfigFiles     State = "config-files"
+	StateHalfInstalled   State = "half-installed"
+	StateUnpacked        State = "unpacked"
+	StateHalfConfigured  State = "half-configured"
+	StateTriggersAwaited State = "triggers-awaited"
+	StateTriggersPending State = "triggers-pending"
+	StateInstalled       State = "installed"
+)
+
+// Status is the parsed `Status` field of a Package, such as
+// `install ok installed`.
+type Status struct {
+	Want  Want
+	Flag  Flag
+	State State
+}
+
+// Parse a Status field, such as `install ok installed`.
+func ParseStatusField(data string) (*Status, error) {
+	ret := Status{}
+	return &ret, ret.UnmarshalControl(data)
+}
+
+func (s *Status) UnmarshalControl(data string) error {
+	parts := strings.Fields(data)
+	if len(parts) != 3 {
+		return fmt.Errorf("Status '%s' does not have three words", data)
+	}
+
+	switch want := Want(parts[0]); want {
+	case WantUnknown, WantInstall, WantHold, WantDeinstall, WantPurge:
+		s.Want = want
+	default:
+		return fmt.Errorf("Unknown want '%s' in Status", parts[0])
+	}
+
+	switch flag := Flag(parts[1]); flag {
+	case FlagOK, FlagReinstReq:
+		s.Flag = flag
+	default:
+		return fmt.Errorf("Unknown flag '%s' in Status", parts[1])
+	}
+
+	switch state := State(parts[2]); state {
+	case StateNotInstalled, StateConfigFiles, StateHalfInstalled,
+		StateUnpacked, StateHalfConfigured, StateTriggersAwaited,
+		StateTriggersPending, StateInstalled:
+		s.State = state
+	default:
+		return fmt.Errorf("Unknown state '%s' in Status", parts[2])
+	}
+
+	return nil
+}
+
+func (s Status) MarshalControl() (string, error) {
+	if s == (Status{}) {
+		return "", nil
+	}
+	return s.String(), nil
+}
+
+func (s Status) String() string {
+	return fmt.Sprintf("%s %s %s", s.Want, s.Flag, s.State)
+}
+
+// }}}
+
+// Conffiles {{{
+
+// Conffile is a single entry of the `Conffiles` field; a configuration
+// file that dpkg is tracking changes to, along with the md5sum of the file
+// as it was shipped.
+type Conffile struct {
+	Path string
+	Hash string
+
+	// Set if the conffile is no longer shipped by the package, but has
+	// been left on disk.
+	Obsolete bool
+
+	// Set if the conffile should be removed on the next upgrade.
+	RemoveOnUpgrade bool
+}
+
+func (c *Conffile) UnmarshalControl(data string) error {
+	parts := strings.Fields(data)
+	if len(parts) < 2 {
+		return fmt.Errorf("Conffile '%s' has no hash", data)
+	}
+	*c = Conffile{Path: parts[0], Hash: parts[1]}
+	for _, flag := range parts[2:] {
+		switch flag {
+		case "obsolete":
+			c.Obsolete = true
+		case "remove-on-upgrade":
+			c.RemoveOnUpgrade = true
+		default:
+			return fmt.Errorf("Unknown flag '%s' on Conffile '%s'", flag, c.Path)
+		}
+	}
+	return nil
+}
+
+func (c Conffile) MarshalControl() (string, error) {
+	ret := c.Path + " " + c.Hash
+	if c.Obsolete {
+		ret += " obsolete"
+	}
+	if c.RemoveOnUpgrade {
+		ret += " remove-on-upgrade"
+	}
+	return ret, nil
+}
+
+// }}}
+
+// Package {{{
+
+// Package is a single entry of the dpkg status (or available) file.
+type Package struct {
+	control.Paragraph
+
+	Package       string `required:"true"`
+	Essential     bool   `control:",omitempty"`
+	Protected     bool   `control:",omitempty"`
+	Status        Status
+	Priority      string
+	Section       string
+	InstalledSize int `control:"Installed-Size,omitempty"`
+	Origin        string
+	Maintainer    string
+	Bugs          string
+	Architecture  dependency.Arch `control:",omitempty"`
+	MultiArch     string          `control:"Multi-Arch"`
+	Source        string
+	Version       version.Version `control:",omitempty"`
+	ConfigVersion version.Version `control:"Config-Version,omitempty"`
+
+	Replaces   dependency.Dependency
+	Provides   dependency.Dependency
+	Depends    dependency.Dependency
+	PreDepends dependency.Dependency `control:"Pre-Depends"`
+	Recommends dependency.Dependency
+	Suggests   dependency.Dependency
+	Breaks     dependency.Dependency
+	Conflicts  dependency.Dependency
+	Enhances   dependency.Dependency
+
+	Conffiles       []Conffile `delim:"\n" strip:"\n\r\t " multiline:"true"`
+	TriggersPending []string   `control:"Triggers-Pending" delim:" "`
+	TriggersAwaited []string   `control:"Triggers-Awaited" delim:" "`
+
+	Description string
+	Homepage    string
+}
+
+// Return the name of the package, qualified with the Architecture if the
+// package is Multi-Arch: same, which is how dpkg names the files in the
+// info directory for this package.
+func (p *Package) InfoName() string {
+	if p.MultiArch == "same" {
+		return p.Package + ":" + p.Architecture.String()
+	}
+	return p.Package
+}
+
+// Return true if dpkg considers the package to be installed; that is, its
+// files are on disk.
+func (p *Package) IsInstalled() bool {
+	switch p.Status.State {
+	case StateInstalled, StateTriggersPending, StateTriggersAwaited,
+		StateHalfConfigured, StateUnpacked, StateHalfInstalled:
+		return true
+	}
+	return false
+}
+
+// }}}
+
+// Parse and write {{{
+
+// Parse a dpkg status (or available) file from the given reader.
+func ParseStatus(reader io.Reader) ([]Package, error) {
+	return control.DecodeAll[Package](reader)
+}
+
+// Parse the dpkg status (or available) file at the given path.
+func ParseStatusFile(path string) ([]Package, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	return ParseStatus(fd)
+}
+
+// Write the given Packages out in the dpkg status file format. Any fields
+// read in that are not modeled on the Package (along with their order) are
+// kept, by way of the Paragraph. Fields that have been cleared on the
+// Package, such as a Config-Version, are removed rather than written back
+// as they were read.
+func WriteStatus(writer io.Writer, packages []Package) error {
+	for i, pkg := range packages {
+		para, err := pkg.paragraph()
+		if err != nil {
+			return err
+		}
+		if i != 0 {
+			if _, err := writer.Write([]byte("\n")); err != nil {
+				return err
+			}
+		}
+		if err := para.WriteTo(writer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the Paragraph to write out for the Package; the fields set on the
+// Package, merged on top of the Paragraph it was read from, less any fields
+// modeled on the Package that are now empty.
+func (p Package) paragraph() (*control.Paragraph, error) {
+	read := p.Paragraph
+	p.Paragraph = control.Paragraph{}
+	fields, err := control.ConvertToParagraph(&p)
+	if err != nil {
+		return nil, err
+	}
+
+	para := read.Update(*fields)
+	for _, key := range packageKeys {
+		if _, ok := fields.Get(key); !ok {
+			para.Delete(key)
+		}
+	}
+	for key, value := range para.Values {
+		/* A trailing newline would be written out as a line with only
+		 * whitespace on it, which ends the entry when read back in. */
+		para.Values[key] = strings.TrimRight(value, "\n")
+	}
+	return &para, nil
+}
+
+// The keys of the fields modeled on the Package.
+var packageKeys = func() []string {
+	ret := []string{}
+	packageType := reflect.TypeOf(Package{})
+	for i := 0; i < packageType.NumField(); i++ {
+		field := packageType.Field(i)
+		if field.Anonymous {
+			continue
+		}
+		key := strings.Split(field.Tag.Get("control"), ",")[0]
+		if key == "" {
+			key = field.Name
+		}
+		ret = append(ret, key)
+	}
+	return ret
+}()
+
+// }}}
+
+// vim: foldmethod=marker
@@ -0,0 +1,170 @@
+package dpkg_test
+
+import (
+	"bytes"
+	"log"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"pault.ag/go/debian/dpkg"
+	"pault.ag/go/debian/version"
+)
+
+/*
+ *
+ */
+
+func isok(t *testing.T, err error) {
+	if err != nil {
+		log.Printf("Error! Error is not nil! %s\n", err)
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+func notok(t *testing.T, err error) {
+	if err == nil {
+		log.Printf("Error! Error is nil!\n")
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+func assert(t *testing.T, expr bool) {
+	if !expr {
+		log.Printf("Assertion failed!")
+		debug.PrintStack()
+		t.FailNow()
+	}
+}
+
+/*
+ *
+ */
+
+const statusFile = `Package: base-files
+Essential: yes
+Status: install ok installed
+Priority: required
+Section: admin
+Installed-Size: 340
+Maintainer: Santiago Vila <sanvila@debian.org>
+Architecture: amd64
+Multi-Arch: foreign
+Version: 12.4+deb12u5
+Replaces: base, dpkg (<= 1.15.0), miscutils
+Provides: base
+Pre-Depends: awk
+Breaks: debian-security-support (<< 2019.04.25), initscripts (<< 2.88dsf-13.3)
+Conffiles:
+ /etc/debian_version 4a1f2bd5f1a9a2ea6a2d1e4d0a37f8f6
+ /etc/dpkg/origins/debian 731423fa8ba067262f8ef37882d1e742
+ /etc/host.conf 4eb63731c9f5e30903ac4fc07a7fe3d6 obsolete
+ /etc/issue 9a4e9a3a6c9b0fd5f5e4b6e0e38c6e8f remove-on-upgrade
+Description: Debian base system miscellaneous files
+ This package contains the basic filesystem hierarchy of a Debian system, and
+ several important miscellaneous files.
+X-Custom: kept
+
+Package: libc6
+Status: install ok triggers-pending
+Priority: optional
+Section: libs
+Installed-Size: 12987
+Maintainer: GNU Libc Maintainers <debian-glibc@lists.debian.org>
+Architecture: amd64
+Multi-Arch: same
+Source: glibc
+Version: 2.36-9+deb12u7
+Config-Version: 2.36-9+deb12u4
+Depends: libgcc-s1
+Triggers-Pending: ldconfig /usr/lib
+Description: GNU C Library: Shared libraries
+
+Package: oldpkg
+Status: deinstall reinstreq config-files
+Architecture: all
+Version: 1.0
+`
+
+func TestParseStatus(t *testing.T) {
+	packages, err := dpkg.ParseStatus(strings.NewReader(statusFile))
+	isok(t, err)
+	assert(t, len(packages) == 3)
+
+	base := packages[0]
+	assert(t, base.Package == "base-files")
+	assert(t, base.Essential)
+	assert(t, base.Status == dpkg.Status{Want: dpkg.WantInstall, Flag: dpkg.FlagOK, State: dpkg.StateInstalled})
+	assert(t, base.InstalledSize == 340)
+	assert(t, base.Version.Version == "12.4+deb12u5")
+	assert(t, len(base.Conffiles) == 4)
+	assert(t, base.Conffiles[0].Path == "/etc/debian_version")
+	assert(t, base.Conffiles[0].Hash == "4a1f2bd5f1a9a2ea6a2d1e4d0a37f8f6")
+	assert(t, !base.Conffiles[0].Obsolete)
+	assert(t, base.Conffiles[2].Obsolete)
+	assert(t, base.Conffiles[3].RemoveOnUpgrade)
+	assert(t, len(base.Breaks.Relations) == 2)
+	assert(t, base.IsInstalled())
+
+	libc := packages[1]
+	assert(t, libc.Status.State == dpkg.StateTriggersPending)
+	assert(t, libc.ConfigVersion.Revision == "9+deb12u4")
+	assert(t, len(libc.TriggersPending) == 2)
+	assert(t, libc.TriggersPending[1] == "/usr/lib")
+	assert(t, libc.InfoName() == "libc6:amd64")
+
+	old := packages[2]
+	assert(t, old.Status.Want == dpkg.WantDeinstall)
+	assert(t, old.Status.Flag == dpkg.FlagReinstReq)
+	assert(t, old.Status.State == dpkg.StateConfigFiles)
+	assert(t, !old.IsInstalled())
+	assert(t, old.InfoName() == "oldpkg")
+}
+
+func TestWriteStatus(t *testing.T) {
+	packages, err := dpkg.ParseStatus(strings.NewReader(statusFile))
+	isok(t, err)
+
+	packages[1].Status.State = dpkg.StateInstalled
+	packages[1].TriggersPending = nil
+
+	out := bytes.Buffer{}
+	isok(t, dpkg.WriteStatus(&out, packages))
+
+	again, err := dpkg.ParseStatus(&out)
+	isok(t, err)
+	assert(t, len(again) == 3)
+	assert(t, again[0].Values["X-Custom"] == "kept")
+	assert(t, len(again[0].Conffiles) == 4)
+	assert(t, again[0].Conffiles[2].Obsolete)
+	assert(t, again[0].Description == packages[0].Description)
+	assert(t, again[1].Status.State == dpkg.StateInstalled)
+	assert(t, len(again[1].TriggersPending) == 0)
+	assert(t, again[1].ConfigVersion.String() == "2.36-9+deb12u4")
+
+	/* Clearing a field drops it, rather than writing back what was read */
+	packages[0].Section = ""
+	packages[1].ConfigVersion = version.Version{}
+	out.Reset()
+	isok(t, dpkg.WriteStatus(&out, packages))
+	assert(t, !strings.Contains(out.String(), "Section: admin"))
+	assert(t, !strings.Contains(out.String(), "Config-Version"))
+	assert(t, strings.Contains(out.String(), "\nX-Custom: kept\n\nPackage: libc6\n"))
+}
+
+func TestParseStatusErrors(t *testing.T) {
+	for _, status := range []string{
+		"install ok",
+		"wibble ok installed",
+		"install broken installed",
+		"install ok exploded",
+	} {
+		_, err := dpkg.ParseStatusField(status)
+		notok(t, err)
+	}
+
+	_, err := dpkg.ParseStatus(strings.NewReader("Package: foo\nConffiles:\n /etc/foo\n"))
+	notok(t, err)
+}
@@ -1,11 +1,12 @@
 module pault.ag/go/debian
 
-go 1.19
+go 1.23
 
 require (
 	github.com/kjk/lzma v0.0.0-20161016003348-3fd93898850d
 	github.com/klauspost/compress v1.16.5
 	github.com/xi2/xz v0.0.0-20171230120015-48954b6210f8
 	golang.org/x/crypto v0.9.0
+	gopkg.in/yaml.v3 v3.0.1
 	pault.ag/go/topsort v0.1.1
 )
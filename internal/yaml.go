@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONToYAML reads JSON into a YAML Node with the same structure (and the
+// same key order), written in block style, so that types with a JSON schema
+// can be marshaled to YAML with the same schema.
+func JSONToYAML(data []byte) (*yaml.Node, error) {
+	document := yaml.Node{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) != 1 {
+		return nil, fmt.Errorf("Expected a single JSON value")
+	}
+	node := document.Content[0]
+	clearStyle(node)
+	return node, nil
+}
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// YAMLToJSON is the inverse of JSONToYAML; it writes a YAML Node out as
+// JSON, keeping the order of mapping keys.
+func YAMLToJSON(node *yaml.Node) ([]byte, error) {
+	out := bytes.Buffer{}
+	if err := writeJSON(&out, node); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func writeJSON(out *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) != 1 {
+			return fmt.Errorf("Expected a single YAML document")
+		}
+		return writeJSON(out, node.Content[0])
+	case yaml.AliasNode:
+		return writeJSON(out, node.Alias)
+	case yaml.MappingNode:
+		out.WriteString("{")
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i != 0 {
+				out.WriteString(",")
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			out.Write(key)
+			out.WriteString(":")
+			if err := writeJSON(out, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+	case yaml.SequenceNode:
+		out.WriteString("[")
+		for i, child := range node.Content {
+			if i != 0 {
+				out.WriteString(",")
+			}
+			if err := writeJSON(out, child); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+	case yaml.ScalarNode:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		out.Write(encoded)
+	default:
+		return fmt.Errorf("Unknown YAML node kind %d", node.Kind)
+	}
+	return nil
+}
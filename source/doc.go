@@ -0,0 +1,32 @@
+/*
+
+Generate the .dsc file of a Debian source package, the same way
+`dpkg-source -b` does, from an unpacked source tree and the tarballs that
+make up the source package.
+
+The Binary, Architecture, Package-List and Build-Depends fields (and
+friends) are taken from debian/control, the Version from the top entry of
+debian/changelog, and the Checksums-* and Files fields are computed by
+reading each tarball.
+
+Here's a trivial example, which writes out a clearsigned .dsc:
+
+	tree, err := source.Open("hello-2.10")
+	if err != nil {
+		panic(err)
+	}
+	dsc, err := tree.DSC("hello_2.10.orig.tar.gz", "hello_2.10-3.debian.tar.xz")
+	if err != nil {
+		panic(err)
+	}
+	fd, err := os.Create(dsc.Filename)
+	if err != nil {
+		panic(err)
+	}
+	defer fd.Close()
+	if err := source.WriteDSC(fd, dsc, signer); err != nil {
+		panic(err)
+	}
+
+*/
+package source // import "pault.ag/go/debian/source"
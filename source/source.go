@@ -0,0 +1,321 @@
+/* {{{ Copyright (c) Paul R. Tagliamonte <paultag@debian.org>, 2015
+ *
+ * Permission is hereby granted, free of charge, to any person obtaining a copy
+ * of this software and associated documentation files (the "Software"), to deal
+ * in the Software without restriction, including without limitation the rights
+ * to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+ * copies of the Software, and to permit persons to whom the Software is
+ * furnished to do so, subject to the following conditions:
+ *
+ * The above copyright notice and this permission notice shall be included in
+ * all copies or substantial portions of the Software.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ * IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+ * FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+ * AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+ * LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+ * OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+ * THE SOFTWARE. }}} */
+
+package source // import "pault.ag/go/debian/source"
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+
+	"pault.ag/go/debian/changelog"
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/dependency"
+	"pault.ag/go/debian/hashio"
+)
+
+// Tree {{{
+
+// A Tree is an unpacked Debian source package, such as a checkout of the
+// packaging repository.
+type Tree struct {
+	Dir string
+
+	Control   *control.Control
+	Changelog *changelog.ChangelogEntry
+
+	// The source format, from debian/source/format. If that file does not
+	// exist, this will be "1.0", as it is for dpkg-source.
+	Format string
+
+	// Set if the tree has a debian/tests/control file, which adds the
+	// autopkgtest test suite to the Testsuite field.
+	Autopkgtest bool
+}
+
+// Read the debian/control file, the top entry of debian/changelog and the
+// source format of the source tree at the given path.
+func Open(dir string) (*Tree, error) {
+	ctrl, err := control.ParseControlFile(filepath.Join(dir, "debian", "control"))
+	if err != nil {
+		return nil, err
+	}
+	entry, err := changelog.ParseFileOne(filepath.Join(dir, "debian", "changelog"))
+	if err != nil {
+		return nil, err
+	}
+
+	tree := Tree{Dir: dir, Control: ctrl, Changelog: entry, Format: "1.0"}
+
+	format, err := ioutil.ReadFile(filepath.Join(dir, "debian", "source", "format"))
+	if err == nil {
+		tree.Format = strings.TrimSpace(string(format))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "debian", "tests", "control")); err == nil {
+		tree.Autopkgtest = true
+	}
+
+	return &tree, nil
+}
+
+// Create the DSC for the source package made up of the given files (the
+// orig and debian tarballs, or the single native tarball). The Filename of
+// the returned DSC is next to the first of those files.
+func (t *Tree) DSC(files ...string) (*control.DSC, error) {
+	dsc, err := Generate(t.Control, t.Changelog, t.Format, files...)
+	if err != nil {
+		return nil, err
+	}
+	if t.Autopkgtest && !contains(dsc.Testsuite, "autopkgtest") {
+		dsc.Testsuite = append(dsc.Testsuite, "autopkgtest")
+	}
+	return dsc, nil
+}
+
+// }}}
+
+// Generate {{{
+
+// Create the DSC of a source package with the given format, from its
+// debian/control, the changelog entry of the version being built and the
+// files that make up the source package.
+func Generate(
+	ctrl *control.Control,
+	entry *changelog.ChangelogEntry,
+	format string,
+	files ...string,
+) (*control.DSC, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No files given for the source package")
+	}
+
+	src := ctrl.Source
+	if src.Source != entry.Source {
+		return nil, fmt.Errorf(
+			"Source '%s' in debian/control does not match '%s' in debian/changelog",
+			src.Source, entry.Source,
+		)
+	}
+
+	maintainer, err := control.ParsePerson(src.Maintainer)
+	if err != nil {
+		return nil, err
+	}
+	uploaders, err := control.ParsePeople(strings.Join(src.Uploaders, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	dsc := control.DSC{
+		Filename: filepath.Join(
+			filepath.Dir(files[0]),
+			fmt.Sprintf("%s_%s.dsc", src.Source, entry.Version.StringWithoutEpoch()),
+		),
+
+		Format:           format,
+		Source:           src.Source,
+		Version:          entry.Version,
+		Maintainer:       maintainer,
+		Uploaders:        uploaders,
+		Homepage:         src.Homepage,
+		StandardsVersion: src.StandardsVersion,
+
+		BuildDepends:        src.BuildDepends,
+		BuildDependsArch:    src.BuildDependsArch,
+		BuildDependsIndep:   src.BuildDependsIndep,
+		BuildConflicts:      src.BuildConflicts,
+		BuildConflictsArch:  src.BuildConflictsArch,
+		BuildConflictsIndep: src.BuildConflictsIndep,
+
+		VcsFields:         src.VcsFields,
+		Testsuite:         src.Testsuite,
+		TestsuiteTriggers: src.TestsuiteTriggers,
+	}
+
+	archs := []dependency.Arch{}
+	for _, binary := range ctrl.Binaries {
+		dsc.Binaries = append(dsc.Binaries, binary.Package)
+		archs = append(archs, binary.Architectures...)
+
+		pkg, err := packageListEntry(src, binary)
+		if err != nil {
+			return nil, err
+		}
+		dsc.PackageList = append(dsc.PackageList, *pkg)
+	}
+	dsc.Architectures = sourceArchitectures(archs)
+
+	for _, path := range files {
+		hashes, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		dsc.Files = append(dsc.Files, control.MD5FileHash{FileHash: hashes[0]})
+		dsc.ChecksumsSha1 = append(dsc.ChecksumsSha1, control.SHA1FileHash{FileHash: hashes[1]})
+		dsc.ChecksumsSha256 = append(dsc.ChecksumsSha256, control.SHA256FileHash{FileHash: hashes[2]})
+	}
+
+	return &dsc, nil
+}
+
+// Return the Package-List entry for a binary package of the source.
+func packageListEntry(src control.SourceParagraph, binary control.BinaryParagraph) (*control.PackageListEntry, error) {
+	ret := control.PackageListEntry{
+		Package:       binary.Package,
+		Type:          "deb",
+		Section:       binary.Section,
+		Priority:      binary.Priority,
+		Architectures: binary.Architectures,
+		Options:       map[string]string{},
+	}
+
+	if it, ok := binary.Get("Package-Type"); ok {
+		ret.Type = it
+	} else if it, ok := binary.Get("XC-Package-Type"); ok {
+		ret.Type = it
+	}
+	if ret.Section == "" {
+		ret.Section = src.Section
+	}
+	if ret.Priority == "" {
+		ret.Priority = src.Priority
+	}
+	if ret.Section == "" {
+		ret.Section = "unknown"
+	}
+	if ret.Priority == "" {
+		ret.Priority = "unknown"
+	}
+	if binary.Essential {
+		ret.Options["essential"] = "yes"
+	}
+
+	if profiles, ok := binary.Get("Build-Profiles"); ok {
+		/* Build-Profiles uses the same syntax as the restriction
+		 * formula of a relation, so borrow the relation parser. */
+		dep, err := dependency.Parse("build-profiles " + profiles)
+		if err != nil {
+			return nil, fmt.Errorf("Package '%s' has bad Build-Profiles: %w", binary.Package, err)
+		}
+		ret.Profiles = dep.Relations[0].Possibilities[0].StageSets
+	}
+
+	return &ret, nil
+}
+
+// Return the Architecture field of the source package, given the
+// Architecture fields of its binary packages. As with dpkg-source, if any
+// binary package is "any", the source is "any" (and "all", if any binary
+// package is "all"); otherwise every architecture is listed, once.
+func sourceArchitectures(archs []dependency.Arch) []dependency.Arch {
+	ret := []dependency.Arch{}
+	seen := map[string]bool{}
+	for _, arch := range archs {
+		if seen[arch.String()] {
+			continue
+		}
+		seen[arch.String()] = true
+		ret = append(ret, arch)
+	}
+
+	if !seen["any"] {
+		return ret
+	}
+	ret = []dependency.Arch{dependency.Any}
+	if seen["all"] {
+		ret = append(ret, dependency.All)
+	}
+	return ret
+}
+
+// Read the file once, returning its md5, sha1 and sha256 FileHashes, in
+// that order.
+func hashFile(path string) ([]control.FileHash, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	writer, hashers, err := hashio.NewHasherWriters([]string{"md5", "sha1", "sha256"}, ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(writer, fd); err != nil {
+		return nil, err
+	}
+
+	ret := []control.FileHash{}
+	for _, hasher := range hashers {
+		ret = append(ret, control.FileHashFromHasher(filepath.Base(path), *hasher))
+	}
+	return ret, nil
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, el := range haystack {
+		if el == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// }}}
+
+// WriteDSC {{{
+
+// Write the DSC out to the given io.Writer. If `signer` is not nil, the
+// .dsc is clearsigned with its private key, which must already be
+// decrypted.
+func WriteDSC(out io.Writer, dsc *control.DSC, signer *openpgp.Entity) error {
+	if signer == nil {
+		return control.Marshal(out, dsc)
+	}
+
+	data := bytes.Buffer{}
+	if err := control.Marshal(&data, dsc); err != nil {
+		return err
+	}
+
+	plaintext, err := clearsign.Encode(out, signer.PrivateKey, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := plaintext.Write(data.Bytes()); err != nil {
+		plaintext.Close()
+		return err
+	}
+	return plaintext.Close()
+}
+
+// }}}
+
+// vim: foldmethod=marker
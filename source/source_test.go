@@ -0,0 +1,208 @@
+package source_test
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/source"
+)
+
+/*
+ *
+ */
+
+func isok(t *testing.T, err error) {
+	if err != nil {
+		log.Printf("Error! Error is not nil! - %s\n", err)
+		t.FailNow()
+	}
+}
+
+func notok(t *testing.T, err error) {
+	if err == nil {
+		log.Printf("Error! Error is nil!\n")
+		t.FailNow()
+	}
+}
+
+func assert(t *testing.T, expr bool) {
+	if !expr {
+		log.Printf("Assertion failed!")
+		t.FailNow()
+	}
+}
+
+/*
+ *
+ */
+
+func writeTree(t *testing.T) (string, []string) {
+	dir := t.TempDir()
+	tree := filepath.Join(dir, "hello-2.10")
+	for name, data := range map[string]string{
+		"debian/control": `Source: hello
+Section: devel
+Priority: optional
+Maintainer: Santiago Vila <sanvila@debian.org>
+Uploaders: John Doe <jdoe@example.com>, "Doe, Jane" <jane@example.com>
+Standards-Version: 4.6.2
+Homepage: https://www.gnu.org/software/hello/
+Vcs-Git: https://salsa.debian.org/sanvila/hello.git -b debian/latest
+Build-Depends: debhelper-compat (= 13)
+Build-Depends-Indep: texinfo
+Build-Conflicts: autoconf2.13
+
+Package: hello
+Architecture: any
+Depends: ${shlibs:Depends}, ${misc:Depends}
+Description: example package based on GNU hello
+
+Package: hello-doc
+Architecture: all
+Section: doc
+Build-Profiles: <!nodoc>
+Description: documentation for hello
+
+Package: hello-udeb
+Architecture: linux-any
+Package-Type: udeb
+Section: debian-installer
+Description: hello for the installer
+`,
+		"debian/changelog": `hello (2.10-3) unstable; urgency=medium
+
+  * Test upload.
+
+ -- Santiago Vila <sanvila@debian.org>  Sun, 01 Jan 2023 12:00:00 +0100
+`,
+		"debian/source/format": "3.0 (quilt)\n",
+		"debian/tests/control": "Test-Command: hello\n",
+	} {
+		path := filepath.Join(tree, name)
+		isok(t, os.MkdirAll(filepath.Dir(path), 0755))
+		isok(t, os.WriteFile(path, []byte(data), 0644))
+	}
+
+	files := []string{
+		filepath.Join(dir, "hello_2.10.orig.tar.gz"),
+		filepath.Join(dir, "hello_2.10-3.debian.tar.xz"),
+	}
+	isok(t, os.WriteFile(files[0], []byte("upstream"), 0644))
+	isok(t, os.WriteFile(files[1], []byte("packaging"), 0644))
+	return tree, files
+}
+
+func packageListLine(t *testing.T, entry control.PackageListEntry) string {
+	line, err := entry.MarshalControl()
+	isok(t, err)
+	return line
+}
+
+func TestDSC(t *testing.T) {
+	dir, files := writeTree(t)
+	tree, err := source.Open(dir)
+	isok(t, err)
+	assert(t, tree.Format == "3.0 (quilt)")
+	assert(t, tree.Autopkgtest)
+
+	dsc, err := tree.DSC(files...)
+	isok(t, err)
+	assert(t, dsc.Filename == filepath.Join(filepath.Dir(files[0]), "hello_2.10-3.dsc"))
+	assert(t, dsc.Format == "3.0 (quilt)")
+	assert(t, dsc.Source == "hello")
+	assert(t, dsc.Version.String() == "2.10-3")
+	assert(t, strings.Join(dsc.Binaries, ",") == "hello,hello-doc,hello-udeb")
+	assert(t, len(dsc.Architectures) == 2)
+	assert(t, dsc.Architectures[0].String() == "any")
+	assert(t, dsc.Architectures[1].String() == "all")
+	assert(t, dsc.Maintainer.Email == "sanvila@debian.org")
+	assert(t, len(dsc.Uploaders) == 2)
+	assert(t, dsc.Uploaders[1].Name == "Doe, Jane")
+	assert(t, dsc.StandardsVersion == "4.6.2")
+	assert(t, dsc.VcsGit.Branch == "debian/latest")
+	assert(t, len(dsc.Testsuite) == 1 && dsc.Testsuite[0] == "autopkgtest")
+	assert(t, len(dsc.BuildDepends.Relations) == 1)
+	assert(t, len(dsc.BuildDependsIndep.Relations) == 1)
+	assert(t, len(dsc.BuildConflicts.Relations) == 1)
+
+	assert(t, len(dsc.PackageList) == 3)
+	assert(t, packageListLine(t, dsc.PackageList[0]) == "hello deb devel optional arch=any")
+	assert(t, packageListLine(t, dsc.PackageList[1]) == "hello-doc deb doc optional arch=all profile=!nodoc")
+	assert(t, packageListLine(t, dsc.PackageList[2]) == "hello-udeb udeb debian-installer optional arch=linux-any")
+
+	assert(t, len(dsc.Files) == 2)
+	assert(t, dsc.Files[0].Filename == "hello_2.10.orig.tar.gz")
+	assert(t, dsc.Files[0].Hash == "bc3b0556316b0ba241ae6bb86b76e8a2")
+	assert(t, dsc.ChecksumsSha1[1].Size == 9)
+	assert(t, len(dsc.ChecksumsSha256) == 2)
+	isok(t, dsc.Verify())
+
+	out := bytes.Buffer{}
+	isok(t, source.WriteDSC(&out, dsc, nil))
+	raw := out.String()
+	assert(t, !strings.Contains(raw, "Filename:"))
+	assert(t, strings.Contains(raw, "\nBinary: hello, hello-doc, hello-udeb\n"))
+	assert(t, strings.Contains(raw, "\nFiles:\n bc3b0556316b0ba241ae6bb86b76e8a2 8 hello_2.10.orig.tar.gz\n"))
+
+	/* The fields must be in the same order dpkg-source writes them */
+	keys := []string{}
+	for _, line := range strings.Split(raw, "\n") {
+		if key, _, ok := strings.Cut(line, ":"); ok && !strings.HasPrefix(line, " ") {
+			keys = append(keys, key)
+		}
+	}
+	assert(t, strings.Join(keys, " ") == strings.Join([]string{
+		"Format", "Source", "Binary", "Architecture", "Version",
+		"Maintainer", "Uploaders", "Homepage", "Standards-Version",
+		"Vcs-Git", "Testsuite",
+		"Build-Depends", "Build-Depends-Indep", "Build-Conflicts",
+		"Package-List", "Checksums-Sha1", "Checksums-Sha256", "Files",
+	}, " "))
+
+	parsed, err := control.ParseDsc(bufio.NewReader(&out), dsc.Filename)
+	isok(t, err)
+	assert(t, parsed.Source == "hello")
+	assert(t, len(parsed.PackageList) == 3)
+	assert(t, parsed.VcsGit.URL == "https://salsa.debian.org/sanvila/hello.git")
+	isok(t, parsed.Verify())
+}
+
+func TestDSCMismatchedSource(t *testing.T) {
+	dir, files := writeTree(t)
+	tree, err := source.Open(dir)
+	isok(t, err)
+	tree.Changelog.Source = "goodbye"
+	_, err = tree.DSC(files...)
+	notok(t, err)
+}
+
+func TestWriteSignedDSC(t *testing.T) {
+	dir, files := writeTree(t)
+	tree, err := source.Open(dir)
+	isok(t, err)
+	dsc, err := tree.DSC(files...)
+	isok(t, err)
+
+	entity, err := openpgp.NewEntity("Santiago Vila", "", "sanvila@debian.org", nil)
+	isok(t, err)
+
+	out := bytes.Buffer{}
+	isok(t, source.WriteDSC(&out, dsc, entity))
+	assert(t, strings.HasPrefix(out.String(), "-----BEGIN PGP SIGNED MESSAGE-----"))
+
+	keyring := openpgp.EntityList{entity}
+	decoder, err := control.NewDecoder(&out, &keyring)
+	isok(t, err)
+	parsed := control.DSC{}
+	isok(t, decoder.Decode(&parsed))
+	assert(t, decoder.Signer() != nil)
+	assert(t, parsed.Source == "hello")
+}
@@ -31,7 +31,6 @@
 package version // import "pault.ag/go/debian/version"
 
 import (
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -67,8 +66,10 @@ func (v *Version) IsNative() bool {
 	return len(v.Revision) == 0
 }
 
-func (version *Version) MarshalText() ([]byte, error) {
-	return json.Marshal(version.String())
+// Version is written out as its string form (such as `1:2.3-1`) when
+// marshaled as text, JSON or YAML.
+func (version Version) MarshalText() ([]byte, error) {
+	return []byte(version.String()), nil
 }
 
 func (version *Version) UnmarshalText(text []byte) error {
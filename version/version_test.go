@@ -31,6 +31,8 @@ package version // import "pault.ag/go/debian/version"
 import (
 	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Abbreviation for creating a new Version object.
@@ -383,4 +385,31 @@ func TestString(t *testing.T) {
 	}
 }
 
+func TestMarshalText(t *testing.T) {
+	text, err := v(1, "2.3", "4").MarshalText()
+	if err != nil || string(text) != "1:2.3-4" {
+		t.Errorf("MarshalText() returned %q, %v", text, err)
+	}
+
+	var ver Version
+	if err := ver.UnmarshalText(text); err != nil || Compare(ver, v(1, "2.3", "4")) != 0 {
+		t.Errorf("UnmarshalText(%q) returned %v, %v", text, ver, err)
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	text, err := yaml.Marshal(map[string]Version{"Version": v(1, "2.3", "4")})
+	if err != nil || string(text) != "Version: 1:2.3-4\n" {
+		t.Errorf("yaml.Marshal() returned %q, %v", text, err)
+	}
+
+	versions := map[string]Version{}
+	if err := yaml.Unmarshal([]byte("Version: \"2.0\"\n"), &versions); err != nil || Compare(versions["Version"], v(0, "2.0", "")) != 0 {
+		t.Errorf("yaml.Unmarshal() returned %v, %v", versions, err)
+	}
+	if err := yaml.Unmarshal([]byte("Version: \"\"\n"), &versions); err == nil {
+		t.Errorf("yaml.Unmarshal() of an empty Version returned no error")
+	}
+}
+
 // vim:ts=4:sw=4:noexpandtab foldmethod=marker